@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/l3uddz/wantarr/config"
+	"github.com/l3uddz/wantarr/database"
+	"github.com/l3uddz/wantarr/metrics"
+	"github.com/l3uddz/wantarr/server"
+	"github.com/l3uddz/wantarr/webhook"
+	"github.com/pkg/errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	apiDefaultLimit = 50
+	apiMaxLimit     = 1000
+	apiWantedTypes  = []string{"missing", "cutoff"}
+)
+
+/* Structs */
+
+type apiPvr struct {
+	Name  string         `json:"name"`
+	Type  string         `json:"type"`
+	URL   string         `json:"url"`
+	Cache map[string]int `json:"cache"`
+}
+
+/* Private */
+
+func registerApiRoutes(srv *server.Server, store database.Store, runs *runManager) {
+	srv.Handle(http.MethodGet, "/api/pvr", apiListPvrs(store))
+	srv.Handle(http.MethodPost, "/api/pvr/", apiTriggerRun(runs))
+	srv.Handle(http.MethodGet, "/api/runs", apiListRuns(store))
+	srv.Handle(http.MethodGet, "/api/history", apiListHistory(store))
+	srv.Handle(http.MethodPost, "/webhook/", apiWebhook(store))
+}
+
+func apiListPvrs(store database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		pvrs := make([]apiPvr, 0)
+
+		for name, pvrConfig := range config.Get().Pvr {
+			p := apiPvr{
+				Name:  name,
+				Type:  pvrConfig.Type,
+				URL:   pvrConfig.URL,
+				Cache: make(map[string]int),
+			}
+
+			for _, wantedType := range apiWantedTypes {
+				p.Cache[wantedType] = store.GetItemsCount(strings.ToLower(name), wantedType)
+			}
+
+			pvrs = append(pvrs, p)
+		}
+
+		sort.Slice(pvrs, func(i, j int) bool {
+			return pvrs[i].Name < pvrs[j].Name
+		})
+
+		server.WriteJSON(w, http.StatusOK, pvrs)
+	}
+}
+
+func apiTriggerRun(runs *runManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// parse path: /api/pvr/{name}/{missing|cutoff}
+		params := server.PathParams(r, "/api/pvr/")
+		if len(params) != 2 {
+			server.WriteError(w, http.StatusNotFound, errors.New("expected /api/pvr/{name}/{missing|cutoff}"))
+			return
+		}
+
+		pvrName := strings.ToLower(params[0])
+		wantedType := strings.ToLower(params[1])
+
+		if _, ok := config.Get().Pvr[pvrName]; !ok {
+			server.WriteError(w, http.StatusNotFound, fmt.Errorf("no pvr configuration found for: %q", pvrName))
+			return
+		}
+
+		// parse options
+		opts := searchOptions{}
+		if err := server.ReadJSON(r, &opts); err != nil {
+			server.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// start run
+		run, err := runs.start(pvrName, wantedType, opts)
+		switch {
+		case err == errRunInProgress:
+			server.WriteError(w, http.StatusConflict, err)
+			return
+		case err != nil:
+			server.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		server.WriteJSON(w, http.StatusAccepted, run)
+	}
+}
+
+func apiListRuns(store database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		runs, err := store.GetSearchRuns(strings.ToLower(q.Get("pvr")), strings.ToLower(q.Get("type")),
+			strings.ToLower(q.Get("status")), apiLimit(r))
+		if err != nil {
+			server.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		server.WriteJSON(w, http.StatusOK, runs)
+	}
+}
+
+func apiListHistory(store database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		history, err := store.GetSearchHistory(strings.ToLower(q.Get("pvr")), strings.ToLower(q.Get("type")),
+			apiLimit(r))
+		if err != nil {
+			server.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		server.WriteJSON(w, http.StatusOK, history)
+	}
+}
+
+func apiWebhook(store database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// parse path: /webhook/{name}
+		params := server.PathParams(r, "/webhook/")
+		if len(params) != 1 {
+			server.WriteError(w, http.StatusNotFound, errors.New("expected /webhook/{name}"))
+			return
+		}
+
+		pvrName := strings.ToLower(params[0])
+		if _, ok := config.Get().Pvr[pvrName]; !ok {
+			server.WriteError(w, http.StatusNotFound, fmt.Errorf("no pvr configuration found for: %q", pvrName))
+			return
+		}
+
+		// parse payload
+		payload := new(webhook.Payload)
+		if err := server.ReadJSON(r, payload); err != nil {
+			server.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// process event
+		result, err := webhook.Process(store, pvrName, payload)
+		if err != nil {
+			server.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		for _, wantedType := range apiWantedTypes {
+			metrics.CachedItems.WithLabelValues(pvrName, wantedType).
+				Set(float64(store.GetItemsCount(pvrName, wantedType)))
+		}
+
+		server.WriteJSON(w, http.StatusOK, result)
+	}
+}
+
+func apiLimit(r *http.Request) int {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		return apiDefaultLimit
+	}
+
+	if limit > apiMaxLimit {
+		return apiMaxLimit
+	}
+
+	return limit
+}
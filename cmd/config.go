@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/l3uddz/wantarr/config"
+	"github.com/l3uddz/wantarr/database"
+	"github.com/l3uddz/wantarr/notify"
+	pvrObj "github.com/l3uddz/wantarr/pvr"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	flagValidateConnect = false
+	flagMigrateWrite    = false
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the config file",
+	Long:  `This command can be used to manage the config file.`,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the config file",
+	Long:  `This command can be used to validate the config file, optionally checking each pvr can be connected to.`,
+
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// load the config here rather than on initialization, so every error is reported
+		cfg, loadErrs, err := config.Load(flagConfigFile)
+		if err != nil {
+			printValidation("config", []error{err})
+			os.Exit(1)
+		}
+
+		invalid := 0
+		if len(loadErrs.Config) > 0 {
+			printValidation("config", loadErrs.Config)
+			invalid++
+		}
+
+		// sort pvrs for a stable report
+		pvrNames := make([]string, 0, len(cfg.Pvr))
+		for name := range cfg.Pvr {
+			pvrNames = append(pvrNames, name)
+		}
+		sort.Strings(pvrNames)
+
+		if len(pvrNames) == 0 {
+			fmt.Println("No pvrs are configured")
+			invalid++
+		}
+
+		// validate pvrs
+		for _, name := range pvrNames {
+			errs := validatePvr(name, cfg.Pvr[name], loadErrs.Pvr[name])
+			printValidation(fmt.Sprintf("pvr %s", name), errs)
+
+			if len(errs) > 0 {
+				invalid++
+			}
+		}
+
+		// validate database
+		if cfg.Database.DSN != "" || len(loadErrs.Database) > 0 {
+			errs := loadErrs.Database
+			if _, _, err := database.ParseDSN(cfg.Database.DSN); err != nil && len(errs) == 0 {
+				errs = append(errs, err)
+			}
+			printValidation("database", errs)
+
+			if len(errs) > 0 {
+				invalid++
+			}
+		}
+
+		// validate notifications
+		for pos, n := range cfg.Notifications {
+			var errs []error
+			if _, err := notify.Get(n); err != nil {
+				errs = append(errs, err)
+			}
+			printValidation(fmt.Sprintf("notification %d", pos), errs)
+
+			if len(errs) > 0 {
+				invalid++
+			}
+		}
+
+		if invalid > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Add new options to the config file",
+	Long:  `This command can be used to show the new options missing from the config file, and add them with --write.`,
+
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		m, err := config.Migrate(flagConfigFile)
+		if err != nil {
+			log.WithError(err).Fatal("Failed migrating config")
+		}
+
+		if len(m.Added) == 0 {
+			fmt.Println("Config file is up to date")
+			return
+		}
+
+		// show changes
+		fmt.Printf("--- %s\n+++ %s (migrated)\n", flagConfigFile, flagConfigFile)
+		for _, line := range diffLines(splitLines(m.Original), splitLines(m.Updated)) {
+			fmt.Println(line)
+		}
+
+		if m.Reformatted {
+			fmt.Println("\nThe new options could not be inserted into the config file as is, " +
+				"so comments and formatting are not preserved")
+		}
+
+		if !flagMigrateWrite {
+			fmt.Println("\nRun again with --write to save these changes")
+			return
+		}
+
+		// write config
+		mode := os.FileMode(0644)
+		if fi, err := os.Stat(flagConfigFile); err == nil {
+			mode = fi.Mode()
+		}
+
+		if err := ioutil.WriteFile(flagConfigFile, m.Updated, mode); err != nil {
+			log.WithError(err).Fatal("Failed writing config")
+		}
+
+		log.WithField("added", strings.Join(m.Added, ", ")).Infof("Saved config file: %q", flagConfigFile)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configMigrateCmd)
+
+	configValidateCmd.Flags().BoolVar(&flagValidateConnect, "connect", false, "Check each pvr can be connected to.")
+	configMigrateCmd.Flags().BoolVar(&flagMigrateWrite, "write", false, "Write the changes to the config file.")
+}
+
+/* Private */
+
+func validatePvr(name string, pvrConfig *config.Pvr, loadErrs []error) []error {
+	errs := append(loadErrs, pvrConfig.Validate()...)
+
+	// validate type
+	pvr, err := pvrObj.Get(name, pvrConfig.Type, pvrConfig)
+	if err != nil {
+		return append(errs, err)
+	}
+
+	if !flagValidateConnect || len(errs) > 0 {
+		return errs
+	}
+
+	// validate connectivity and version
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := pvr.Init(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+func printValidation(name string, errs []error) {
+	if len(errs) == 0 {
+		fmt.Printf("%s: ok\n", name)
+		return
+	}
+
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, fmt.Sprintf("  - %v", err))
+	}
+
+	fmt.Printf("%s: invalid\n%s\n", name, strings.Join(msgs, "\n"))
+}
+
+func splitLines(data []byte) []string {
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// diffLines returns the lines of b prefixed with "+" when added or " " when unchanged, and removed lines of a with "-"
+func diffLines(a []string, b []string) []string {
+	// longest common subsequence lengths
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// walk both
+	lines := make([]string, 0, len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, " "+a[i])
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			lines = append(lines, "+"+b[j])
+			j++
+		default:
+			lines = append(lines, "-"+a[i])
+			i++
+		}
+	}
+
+	return lines
+}
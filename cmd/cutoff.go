@@ -1,12 +1,7 @@
 package cmd
 
 import (
-	"github.com/l3uddz/wantarr/database"
-	pvrObj "github.com/l3uddz/wantarr/pvr"
-	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"github.com/tommysolsen/capitalise"
-	"time"
 )
 
 var cutoffCmd = &cobra.Command{
@@ -16,175 +11,22 @@ var cutoffCmd = &cobra.Command{
 
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		// validate inputs
-		if err := parseValidateInputs(args); err != nil {
-			log.WithError(err).Fatal("Failed validating inputs")
-		}
-
-		// init pvr object
-		if err := pvr.Init(); err != nil {
-			log.WithError(err).Fatalf("Failed initializing pvr object for: %s", pvrName)
-		}
-
-		// load database
-		if err := database.Init(flagDatabaseFile); err != nil {
-			log.WithError(err).Fatal("Failed opening database file")
-		}
-		defer database.Close()
-
-		// retrieve cutoff records from pvr and stash in database
-		existingItemsCount := database.GetItemsCount(lowerPvrName, "cutoff")
-		if flagRefreshCache || existingItemsCount < 1 {
-			log.Infof("Retrieving cutoff unmet media from %s: %q", capitalise.First(pvrConfig.Type), pvrName)
-
-			cutoffRecords, err := pvr.GetWantedCutoff()
-			if err != nil {
-				log.WithError(err).Fatal("Failed retrieving wanted cutoff unmet pvr items...")
-			}
-
-			// stash cutoff media in database
-			log.Debug("Stashing media items in database...")
-
-			if err := database.SetMediaItems(lowerPvrName, "cutoff", cutoffRecords); err != nil {
-				log.WithError(err).Fatal("Failed stashing media items in database")
-			}
-
-			log.Info("Stashed media items")
-
-			// remove media no longer cutoff unmet
-			if existingItemsCount >= 1 {
-				log.Debug("Removing media items from database that are no longer cutoff unmet...")
-
-				removedItems, err := database.DeleteMissingItems(lowerPvrName, "cutoff", cutoffRecords)
-				if err != nil {
-					log.WithError(err).Fatal("Failed removing media items from database that are no longer cutoff unmet...")
-				}
-
-				log.WithField("removed_items", removedItems).
-					Info("Removed media items from database that are no longer cutoff unmet")
-			}
-		}
-
-		// start queue monitor
-		if maxQueueSize > 0 {
-			go func() {
-				log.Info("Started queue monitor")
-				for {
-					// retrieve queue size
-					qs, err := pvr.GetQueueSize()
-					if err != nil {
-						log.WithError(err).Error("Failed retrieving queue size, aborting...")
-						continueRunning.Store(false)
-						break
-					}
-
-					// check queue size
-					if qs >= maxQueueSize {
-						log.Warnf("Queue size has been reached, aborting....")
-						continueRunning.Store(false)
-						break
-					}
-
-					// sleep before check
-					time.Sleep(10 * time.Second)
-				}
-				log.Info("Finished queue monitor")
-			}()
-		}
-
-		// get media items from database
-		mediaItems, err := database.GetMediaItems(lowerPvrName, "cutoff", false)
-		if err != nil {
-			log.WithError(err).Fatal("Failed retrieving media items from database...")
-		}
-		log.WithField("media_items", len(mediaItems)).Debug("Retrieved media items from database")
-
-		// start searching
-		var searchItems []pvrObj.MediaItem
-		searchedItemsCount := 0
-
-		for _, item := range mediaItems {
-			// abort if required (queue monitor will set this)
-			if !continueRunning.Load() {
-				break
-			}
-
-			// dont search this item if we already searched it within N days
-			if item.LastSearchDateUtc != nil && !item.LastSearchDateUtc.IsZero() {
-				retryAfterDate := item.LastSearchDateUtc.Add((24 * time.Hour) * pvrConfig.RetryDaysAge.Cutoff)
-				if time.Now().UTC().Before(retryAfterDate) {
-					log.WithField("retry_min_date", retryAfterDate).
-						Tracef("Skipping media item %v until allowed retry date", item.Id)
-					continue
-				}
-			}
-
-			// add item to batch
-			searchItems = append(searchItems, pvrObj.MediaItem{
-				ItemId:     item.Id,
-				AirDateUtc: item.AirDateUtc,
-			})
-
-			// not enough items batched yet
-			batchedItemsCount := len(searchItems)
-			if batchedItemsCount < searchBatchSize {
-				continue
-			}
-
-			// do search
-			log.WithFields(logrus.Fields{
-				"search_items": batchedItemsCount,
-			}).Info("Searching...")
-
-			searchedItemsCount += batchedItemsCount
-
-			if _, err := searchForItems(searchItems, "cutoff"); err != nil {
-				log.WithError(err).Error("Failed searching for items...")
-			} else {
-				log.WithFields(logrus.Fields{
-					"searched_items": searchedItemsCount,
-				}).Info("Search complete")
-			}
-
-			// reset batch
-			searchItems = []pvrObj.MediaItem{}
-
-			// max search items reached?
-			if maxSearchItems > 0 && searchedItemsCount >= maxSearchItems {
-				log.WithField("searched_items", searchedItemsCount).
-					Info("Max search items reached, aborting...")
-				break
-			}
-
-			// sleep before next batch
-			time.Sleep(5 * time.Second)
-		}
-
-		// search for any leftover items from batching
-		if continueRunning.Load() && len(searchItems) > 0 {
-			// search items
-			log.WithFields(logrus.Fields{
-				"search_items": len(searchItems),
-			}).Info("Searching...")
-
-			searchedItemsCount += len(searchItems)
-
-			if _, err := searchForItems(searchItems, "cutoff"); err != nil {
-				log.WithError(err).Error("Failed searching for items...")
-			} else {
-				log.WithFields(logrus.Fields{
-					"searched_items": searchedItemsCount,
-				}).Info("Search complete")
-			}
-		}
+		runSearchJob(args[0], "cutoff")
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(cutoffCmd)
 
-	cutoffCmd.Flags().IntVarP(&maxQueueSize, "queue-size", "q", 0, "Exit when queue size reached.")
-	cutoffCmd.Flags().IntVarP(&maxSearchItems, "max-search", "m", 0, "Exit when this many items have been searched.")
-	cutoffCmd.Flags().IntVarP(&searchBatchSize, "search-size", "s", 10, "How many items to search at once.")
+	cutoffCmd.Flags().IntVarP(&maxQueueSize, "queue-size", "q", 0, "Exit when queue size reached (default search.queue_size, -1 to disable).")
+	cutoffCmd.Flags().StringSliceVar(&queueStates, "queue-states", nil, "Only count queue items in these states towards queue size.")
+	cutoffCmd.Flags().DurationVar(&queueWait, "queue-wait", 0, "Pause instead of exiting when queue size reached, for up to this long.")
+	cutoffCmd.Flags().IntVar(&queueResumeSize, "queue-resume", 0, "Resume paused searching once queue size drops below this (default half of queue-size).")
+	cutoffCmd.Flags().IntVarP(&maxSearchItems, "max-search", "m", 0, "Exit when this many items have been searched (default search.max_search, -1 to disable).")
+	cutoffCmd.Flags().IntVarP(&searchBatchSize, "search-size", "s", 0, "How many items to search at once (default search.search_size or 10).")
+	cutoffCmd.Flags().DurationVar(&batchDelay, "batch-delay", 0, "How long to wait between search batches (default search.batch_delay or 5s).")
+	cutoffCmd.Flags().StringVar(&flagReport, "report", "", "Write a run report in this format (json, yaml).")
+	cutoffCmd.Flags().StringVar(&flagReportFile, "report-file", "", "Write the run report to this file (default stdout).")
+	cutoffCmd.Flags().StringVar(&flagMetricsFile, "metrics-file", "", "Write prometheus metrics to this textfile-collector file.")
 	cutoffCmd.Flags().BoolVarP(&flagRefreshCache, "refresh-cache", "r", false, "Refresh the locally stored cache.")
 }
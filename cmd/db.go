@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/l3uddz/wantarr/config"
+	"github.com/l3uddz/wantarr/database"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the database",
+	Long:  `This command can be used to manage the database schema.`,
+}
+
+var dbStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the database schema version",
+	Long:  `This command can be used to show the applied and pending database schema migrations.`,
+
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		store, m := openMigrator()
+		defer closeStore(store)
+
+		migrations, err := m.Migrations()
+		if err != nil {
+			log.WithError(err).Fatal("Failed retrieving database migrations")
+		}
+
+		// show migrations
+		current, pending := 0, 0
+		for _, migration := range migrations {
+			status := "pending"
+			if migration.AppliedUtc != nil {
+				status = fmt.Sprintf("applied %s", migration.AppliedUtc.Format(time.RFC3339))
+				current = migration.Version
+			} else {
+				pending++
+			}
+
+			fmt.Printf("%4d  %-30s  %s\n", migration.Version, migration.Name, status)
+		}
+
+		fmt.Printf("\nSchema version: %d (latest: %d, pending: %d)\n", current,
+			migrations[len(migrations)-1].Version, pending)
+	},
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply pending database migrations",
+	Long:  `This command can be used to apply pending database schema migrations, which is also done when searching or serving.`,
+
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		store, m := openMigrator()
+		defer closeStore(store)
+
+		applied, err := m.Migrate()
+		for _, migration := range applied {
+			fmt.Printf("%4d  %-30s  applied\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.WithError(err).Fatal("Failed migrating database")
+		}
+
+		if len(applied) == 0 {
+			fmt.Println("Database schema is up to date")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(dbStatusCmd)
+	dbCmd.AddCommand(dbMigrateCmd)
+}
+
+/* Private */
+
+func openMigrator() (database.Store, database.Migrator) {
+	store, err := database.Open(flagDatabaseFile, config.Get().Database.DSN, false)
+	if err != nil {
+		log.WithError(err).Fatal("Failed opening database")
+	}
+
+	m, ok := store.(database.Migrator)
+	if !ok {
+		closeStore(store)
+		log.Fatal("Database does not have a versioned schema")
+	}
+
+	return store, m
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/l3uddz/wantarr/database"
+	pvrObj "github.com/l3uddz/wantarr/pvr"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+/* Consts */
+
+const (
+	// report formats
+	reportFormatJson = "json"
+	reportFormatYaml = "yaml"
+
+	// skipped item reasons
+	skipReasonRetryAge      = "retry_age"
+	skipReasonFutureAirDate = "future_air_date"
+	skipReasonRunStopped    = "run_stopped"
+)
+
+/* Structs */
+
+type runReport struct {
+	Run     *database.SearchRun `json:"run"`
+	Options searchOptions       `json:"options"`
+	Batches []reportBatch       `json:"batches"`
+	Skipped []reportSkippedItem `json:"skipped"`
+
+	mtx sync.Mutex
+}
+
+type reportBatch struct {
+	Items     []int     `json:"items"`
+	CommandId int       `json:"command_id,omitempty"`
+	Status    string    `json:"status"`
+	Message   string    `json:"message,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Started   time.Time `json:"started"`
+	Ended     time.Time `json:"ended"`
+}
+
+type reportSkippedItem struct {
+	Id         int        `json:"id"`
+	Reason     string     `json:"reason"`
+	AirDate    time.Time  `json:"air_date"`
+	LastSearch *time.Time `json:"last_search,omitempty"`
+	RetryAfter *time.Time `json:"retry_after,omitempty"`
+}
+
+/* Private */
+
+func newRunReport(format string, opts searchOptions) (*runReport, error) {
+	switch strings.ToLower(format) {
+	case "":
+		// reporting disabled
+		return nil, nil
+	case reportFormatJson, reportFormatYaml:
+		break
+	default:
+		return nil, fmt.Errorf("unsupported report format: %q (supported: %s, %s)", format, reportFormatJson,
+			reportFormatYaml)
+	}
+
+	return &runReport{
+		Options: opts,
+		Batches: make([]reportBatch, 0),
+		Skipped: make([]reportSkippedItem, 0),
+	}, nil
+}
+
+func (r *runReport) addBatch(itemIds []int, command *pvrObj.SearchCommand, started time.Time, err error) {
+	if r == nil {
+		return
+	}
+
+	batch := reportBatch{
+		Items:   itemIds,
+		Started: started,
+		Ended:   time.Now().UTC(),
+	}
+
+	if command != nil {
+		batch.CommandId = command.Id
+		batch.Status = command.Status
+		batch.Message = command.Message
+		if !command.Started.IsZero() {
+			batch.Started = command.Started
+		}
+		if !command.Ended.IsZero() {
+			batch.Ended = command.Ended
+		}
+	} else if err != nil {
+		batch.Status = "error"
+	}
+
+	if err != nil {
+		batch.Error = err.Error()
+	}
+
+	r.mtx.Lock()
+	r.Batches = append(r.Batches, batch)
+	r.mtx.Unlock()
+}
+
+func (r *runReport) addSkipped(item database.MediaItem, reason string, retryAfter *time.Time) {
+	if r == nil {
+		return
+	}
+
+	r.mtx.Lock()
+	r.Skipped = append(r.Skipped, reportSkippedItem{
+		Id:         item.Id,
+		Reason:     reason,
+		AirDate:    item.AirDateUtc,
+		LastSearch: item.LastSearchDateUtc,
+		RetryAfter: retryAfter,
+	})
+	r.mtx.Unlock()
+}
+
+func (r *runReport) write(format string, filePath string, run *database.SearchRun) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.Run = run
+
+	// encode report
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed encoding report")
+	}
+
+	if strings.EqualFold(format, reportFormatYaml) {
+		// json is valid yaml, decoding into a map slice keeps the field names and order
+		var ms yaml.MapSlice
+		if err := yaml.Unmarshal(data, &ms); err != nil {
+			return errors.Wrap(err, "failed decoding report")
+		}
+
+		if data, err = yaml.Marshal(ms); err != nil {
+			return errors.Wrap(err, "failed encoding report")
+		}
+	} else {
+		data = append(data, '\n')
+	}
+
+	// write report
+	if filePath == "" || filePath == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	if err := ioutil.WriteFile(filePath, data, 0644); err != nil {
+		return errors.Wrapf(err, "failed writing report to file: %q", filePath)
+	}
+
+	return nil
+}
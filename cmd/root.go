@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"github.com/l3uddz/wantarr/build"
 	"github.com/l3uddz/wantarr/config"
@@ -9,13 +10,12 @@ import (
 	pvrObj "github.com/l3uddz/wantarr/pvr"
 	"github.com/l3uddz/wantarr/utils/paths"
 	stringutils "github.com/l3uddz/wantarr/utils/strings"
-	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"go.uber.org/atomic"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"strings"
+	"syscall"
 	"time"
 )
 
@@ -26,21 +26,30 @@ var (
 	flagConfigFile   = "config.yaml"
 	flagDatabaseFile = "vault.db"
 	flagLogFile      = "activity.log"
+	flagLogFormat    = ""
+	flagLogFileFmt   = ""
 	flagRefreshCache = false
+	flagMetricsFile  = ""
+	flagReport       = ""
+	flagReportFile   = ""
 
 	// Global vars
-	pvrName         string
-	lowerPvrName    string
-	pvrConfig       *config.Pvr
-	pvr             pvrObj.Interface
-	log             *logrus.Entry
-	continueRunning *atomic.Bool
+	log *logrus.Entry
 
 	maxQueueSize    int
+	queueStates     []string
+	queueResumeSize int
+	queueWait       time.Duration
 	searchBatchSize int
+	batchDelay      time.Duration
 	maxSearchItems  int
 )
 
+const (
+	// exit code used when a run was stopped by an interrupt signal
+	exitCodeInterrupted = 130
+)
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "wantarr",
@@ -60,18 +69,22 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		initConfig(cmd)
+	}
 
 	// Parse persistent flags
 	rootCmd.PersistentFlags().StringVar(&flagConfigFolder, "config-dir", flagConfigFolder, "Config folder")
 	rootCmd.PersistentFlags().StringVarP(&flagConfigFile, "config", "c", flagConfigFile, "Config file")
-	rootCmd.PersistentFlags().StringVarP(&flagDatabaseFile, "database", "d", flagDatabaseFile, "Database file")
+	rootCmd.PersistentFlags().StringVarP(&flagDatabaseFile, "database", "d", flagDatabaseFile, "Database file (:memory: to keep state in memory only)")
 	rootCmd.PersistentFlags().StringVarP(&flagLogFile, "log", "l", flagLogFile, "Log file")
+	rootCmd.PersistentFlags().StringVar(&flagLogFormat, "log-format", flagLogFormat, "Log format (text, json)")
+	rootCmd.PersistentFlags().StringVar(&flagLogFileFmt, "log-file-format", flagLogFileFmt, "Log file format (defaults to log-format)")
 	rootCmd.PersistentFlags().CountVarP(&flagLogLevel, "verbose", "v", "Verbose level")
 
 }
 
-func initConfig() {
+func initConfig(cmd *cobra.Command) {
 	// Set core variables
 	if !rootCmd.PersistentFlags().Changed("config") {
 		flagConfigFile = filepath.Join(flagConfigFolder, flagConfigFile)
@@ -84,46 +97,105 @@ func initConfig() {
 	}
 
 	// Init Logging
-	if err := logger.Init(flagLogLevel, flagLogFile); err != nil {
+	log = logger.GetLogger("app")
+
+	if err := logger.Init(flagLogLevel, flagLogFile, flagLogFormat, flagLogFileFmt); err != nil {
 		log.WithError(err).Fatal("Failed to initialize logging")
 	}
 
-	log = logger.GetLogger("app")
-
 	log.Infof("Using %s = %s (%s@%s)", stringutils.StringLeftJust("VERSION", " ", 10),
 		build.Version, build.GitCommit, build.Timestamp)
-	logger.ShowUsing()
+
+	// config validate loads the config itself to report every error, so logs with the default settings
+	if cmd == configValidateCmd {
+		if err := logger.Configure(logger.Settings{}); err != nil {
+			log.WithError(err).Fatal("Failed to initialize logging")
+		}
+
+		logger.ShowUsing()
+		return
+	}
 
 	// Init Config
 	if err := config.Init(flagConfigFile); err != nil {
 		log.WithError(err).Fatal("Failed to initialize config")
 	}
 
-	// Init Globals
-	continueRunning = atomic.NewBool(true)
+	// use log formats from config unless set by flags
+	logFormat, logFileFormat := flagLogFormat, flagLogFileFmt
+	if logFormat == "" {
+		logFormat = config.Get().Logging.Format
+	}
+	if logFileFormat == "" {
+		logFileFormat = config.Get().Logging.FileFormat
+	}
+
+	if logFormat != flagLogFormat || logFileFormat != flagLogFileFmt {
+		if err := logger.SetFormat(logFormat, logFileFormat); err != nil {
+			log.WithError(err).Fatal("Failed to initialize logging")
+		}
+	}
+
+	// set log destinations from config
+	logging := config.Get().Logging
+	if err := logger.Configure(logger.Settings{
+		Console: logging.Console,
+		File: logger.FileSettings{
+			Disabled:   logging.File.Disabled,
+			MaxSize:    logging.File.MaxSize,
+			MaxBackups: logging.File.MaxBackups,
+			MaxAge:     logging.File.MaxAge,
+			PerPvr:     logging.File.PerPvr,
+		},
+		Syslog: logger.SyslogSettings{
+			Enabled: logging.Syslog.Enabled,
+			Network: logging.Syslog.Network,
+			Address: logging.Syslog.Address,
+			Tag:     logging.Syslog.Tag,
+		},
+	}); err != nil {
+		log.WithError(err).Fatal("Failed to initialize logging")
+	}
+
+	logger.ShowUsing()
 }
 
 /* Private Helpers */
 
-func parseValidateInputs(args []string) error {
-	// validate pvr exists in config
-	pvrName = args[0]
-	lowerPvrName = strings.ToLower(pvrName)
-	pc, ok := config.Config.Pvr[pvrName]
-	if !ok {
-		return fmt.Errorf("no pvr configuration found for: %q", pvrName)
-	}
+func notifyInterrupt(onInterrupt func()) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
 
-	pvrConfig = pc
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
 
-	// init pvrObj
-	p, err := pvrObj.Get(pvrName, pvrConfig.Type, pvrConfig)
-	if err != nil {
-		return errors.WithMessage(err, "failed loading pvr object")
-	}
+	go func() {
+		defer signal.Stop(sigs)
 
-	pvr = p
-	return nil
+		// first signal stops once the current batch has finished
+		select {
+		case sig := <-sigs:
+			log.Warnf("Received %s, stopping after the current batch (repeat to abandon it)...", sig)
+			onInterrupt()
+		case <-ctx.Done():
+			return
+		}
+
+		// second signal abandons the current batch
+		select {
+		case sig := <-sigs:
+			log.Warnf("Received %s, abandoning the current batch...", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+func closeStore(store database.Store) {
+	if err := store.Close(); err != nil {
+		log.WithError(err).Error("Failed closing database gracefully...")
+	}
 }
 
 func pluckMediaItemIds(mediaItems []pvrObj.MediaItem) []int {
@@ -135,27 +207,3 @@ func pluckMediaItemIds(mediaItems []pvrObj.MediaItem) []int {
 
 	return mediaItemIds
 }
-
-func searchForItems(searchItems []pvrObj.MediaItem, wantedType string) (bool, error) {
-	// set variables required for search
-	searchItemIds := pluckMediaItemIds(searchItems)
-	searchTime := time.Now().UTC()
-
-	ok, err := pvr.SearchMediaItems(searchItemIds)
-	if err != nil {
-		return false, err
-	} else if !ok {
-		return false, errors.New("failed unexpectedly searching for items")
-	} else {
-		// update search items lastsearch time
-		for pos := range searchItems {
-			(&searchItems[pos]).LastSearch = searchTime
-		}
-
-		if err := database.SetMediaItems(lowerPvrName, wantedType, searchItems); err != nil {
-			log.WithError(err).Fatal("Failed updating search items in database")
-		}
-	}
-
-	return true, nil
-}
@@ -0,0 +1,764 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/l3uddz/wantarr/config"
+	"github.com/l3uddz/wantarr/database"
+	"github.com/l3uddz/wantarr/metrics"
+	"github.com/l3uddz/wantarr/notify"
+	pvrObj "github.com/l3uddz/wantarr/pvr"
+	"github.com/l3uddz/wantarr/utils/lists"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/tommysolsen/capitalise"
+	"go.uber.org/atomic"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* Consts */
+
+const (
+	defaultSearchBatchSize  = 10
+	defaultSearchBatchDelay = 5 * time.Second
+
+	// search run statuses
+	runStatusRunning     = "running"
+	runStatusCompleted   = "completed"
+	runStatusAborted     = "aborted"
+	runStatusInterrupted = "interrupted"
+	runStatusFailed      = "failed"
+
+	// search run stop reasons
+	stopReasonQueueFull       = "queue size reached"
+	stopReasonQueueError      = "failed retrieving queue"
+	stopReasonMaxSearch       = "max search items reached"
+	stopReasonCommandTimeout  = "search command timed out"
+	stopReasonFreeSpace       = "root folder free disk space below threshold"
+	stopReasonDownloadClients = "no download clients enabled"
+	stopReasonThrottleError   = "failed retrieving throttle status"
+	stopReasonInterrupted     = "interrupted"
+	stopReasonDatabaseError   = "failed updating search items in database"
+	stopReasonNotFinished     = "not finished before wantarr stopped"
+)
+
+/* Structs */
+
+type searchOptions struct {
+	RefreshCache bool     `json:"refresh_cache"`
+	QueueSize    int      `json:"queue_size"`
+	QueueStates  []string `json:"queue_states"`
+	QueueResume  int      `json:"queue_resume"`
+	QueueWait    string   `json:"queue_wait"`
+	MaxSearch    int      `json:"max_search"`
+	SearchSize   int      `json:"search_size"`
+	BatchDelay   string   `json:"batch_delay"`
+}
+
+type searchJob struct {
+	pvrName      string
+	lowerPvrName string
+	pvrConfig    *config.Pvr
+	pvr          pvrObj.Interface
+	store        database.Store
+	log          *logrus.Entry
+
+	wantedType    string
+	wantedDesc    string
+	excludeFuture bool
+	retryAge      time.Duration
+	getWanted     func(context.Context) ([]pvrObj.MediaItem, error)
+
+	refreshCache     bool
+	maxQueueSize     int
+	queueStates      []string
+	queueResumeSize  int
+	queueWait        time.Duration
+	queueWaitExpires time.Time
+	maxSearchItems   int
+	searchBatchSize  int
+	batchDelay       time.Duration
+
+	continueRunning *atomic.Bool
+	searchPaused    *atomic.Bool
+	interrupted     *atomic.Bool
+	stopReason      string
+	stopMtx         sync.Mutex
+
+	record *database.SearchRun
+	report *runReport
+}
+
+/* Public */
+
+func runSearchJob(pvrName string, wantedType string) {
+	// validate inputs
+	opts := searchOptions{
+		RefreshCache: flagRefreshCache,
+		QueueSize:    maxQueueSize,
+		QueueStates:  queueStates,
+		QueueResume:  queueResumeSize,
+		QueueWait:    queueWait.String(),
+		MaxSearch:    maxSearchItems,
+		SearchSize:   searchBatchSize,
+		BatchDelay:   batchDelay.String(),
+	}
+
+	// load database
+	store, err := database.Open(flagDatabaseFile, config.Get().Database.DSN, true)
+	if err != nil {
+		log.WithError(err).Fatal("Failed opening database")
+	}
+
+	job, err := newSearchJob(store, pvrName, wantedType, opts)
+	if err != nil {
+		log.WithError(err).Fatal("Failed validating inputs")
+	}
+
+	if job.report, err = newRunReport(flagReport, opts); err != nil {
+		log.WithError(err).Fatal("Failed validating inputs")
+	}
+
+	// stop searching on interrupt
+	ctx, cancel := notifyInterrupt(job.interrupt)
+	defer cancel()
+
+	// run search (pvr initialization errors are recorded as the run failing)
+	err = job.run(ctx)
+	closeStore(store)
+
+	// write report
+	if job.report != nil {
+		if err := job.report.write(flagReport, flagReportFile, job.record); err != nil {
+			log.WithError(err).Error("Failed writing report")
+		}
+	}
+
+	// write metrics
+	if flagMetricsFile != "" {
+		if err := metrics.WriteTextfile(flagMetricsFile); err != nil {
+			log.WithError(err).Error("Failed writing metrics file")
+		}
+	}
+
+	if err != nil {
+		job.log.WithError(err).Fatalf("Failed searching for %s media", job.wantedDesc)
+	}
+
+	if job.interrupted.Load() {
+		job.log.Warn("Search was interrupted")
+		cancel()
+		os.Exit(exitCodeInterrupted)
+	}
+}
+
+/* Private */
+
+func newSearchJob(store database.Store, pvrName string, wantedType string, opts searchOptions) (*searchJob, error) {
+	// validate pvr exists in config
+	pvrConfig, ok := config.Get().Pvr[pvrName]
+	if !ok {
+		return nil, fmt.Errorf("no pvr configuration found for: %q", pvrName)
+	}
+
+	// init pvrObj
+	pvr, err := pvrObj.Get(pvrName, pvrConfig.Type, pvrConfig)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed loading pvr object")
+	}
+
+	job := &searchJob{
+		pvrName:      pvrName,
+		lowerPvrName: strings.ToLower(pvrName),
+		pvrConfig:    pvrConfig,
+		pvr:          pvr,
+		store:        store,
+		log: log.WithFields(logrus.Fields{
+			"pvr":         strings.ToLower(pvrName),
+			"wanted_type": wantedType,
+		}),
+
+		wantedType: wantedType,
+
+		refreshCache:    opts.RefreshCache,
+		maxQueueSize:    opts.QueueSize,
+		queueStates:     opts.QueueStates,
+		queueResumeSize: opts.QueueResume,
+		maxSearchItems:  opts.MaxSearch,
+		searchBatchSize: opts.SearchSize,
+
+		continueRunning: atomic.NewBool(true),
+		searchPaused:    atomic.NewBool(false),
+		interrupted:     atomic.NewBool(false),
+	}
+
+	// use pvr search settings when not set (negative queue / max search sizes disable them)
+	if job.maxQueueSize == 0 {
+		job.maxQueueSize = pvrConfig.Search.QueueSize
+	}
+	if job.maxSearchItems == 0 {
+		job.maxSearchItems = pvrConfig.Search.MaxSearch
+	}
+	if job.searchBatchSize <= 0 {
+		job.searchBatchSize = pvrConfig.Search.SearchSize
+	}
+
+	// validate search size
+	if job.searchBatchSize <= 0 {
+		job.searchBatchSize = defaultSearchBatchSize
+	}
+
+	// validate batch delay
+	if opts.BatchDelay != "" {
+		d, err := time.ParseDuration(opts.BatchDelay)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid batch delay duration: %q", opts.BatchDelay)
+		}
+		job.batchDelay = d
+	}
+	if job.batchDelay <= 0 {
+		job.batchDelay = pvrConfig.Search.BatchDelay
+	}
+	if job.batchDelay <= 0 {
+		job.batchDelay = defaultSearchBatchDelay
+	}
+
+	// validate queue wait
+	if opts.QueueWait != "" {
+		d, err := time.ParseDuration(opts.QueueWait)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid queue wait duration: %q", opts.QueueWait)
+		}
+		job.queueWait = d
+	}
+
+	// validate queue states
+	for _, state := range job.queueStates {
+		if !lists.StringListContains(pvrObj.QueueStates, state, false) {
+			return nil, fmt.Errorf("unsupported queue state: %q (supported: %s)", state,
+				strings.Join(pvrObj.QueueStates, ", "))
+		}
+	}
+
+	// set queue low-water mark
+	if job.queueResumeSize <= 0 {
+		job.queueResumeSize = job.maxQueueSize / 2
+		if job.queueResumeSize < 1 {
+			job.queueResumeSize = 1
+		}
+	} else if job.maxQueueSize > 0 && job.queueResumeSize > job.maxQueueSize {
+		return nil, fmt.Errorf("queue resume size must not be greater than queue size: %d > %d",
+			job.queueResumeSize, job.maxQueueSize)
+	}
+
+	// set wanted type specifics
+	switch wantedType {
+	case "missing":
+		job.wantedDesc = "missing"
+		job.excludeFuture = true
+		job.retryAge = pvrConfig.RetryDaysAge.Missing.Duration()
+		job.getWanted = pvr.GetWantedMissing
+	case "cutoff":
+		job.wantedDesc = "cutoff unmet"
+		job.retryAge = pvrConfig.RetryDaysAge.Cutoff.Duration()
+		job.getWanted = pvr.GetWantedCutoff
+	default:
+		return nil, fmt.Errorf("unsupported wanted type: %q", wantedType)
+	}
+
+	return job, nil
+}
+
+func (j *searchJob) interrupt() {
+	j.interrupted.Store(true)
+	j.stop(stopReasonInterrupted)
+}
+
+func (j *searchJob) stop(reason string) {
+	j.stopMtx.Lock()
+	defer j.stopMtx.Unlock()
+
+	// only the first reason is kept
+	if j.stopReason == "" {
+		j.stopReason = reason
+	}
+	j.continueRunning.Store(false)
+}
+
+func (j *searchJob) run(ctx context.Context) error {
+	// record run
+	if err := j.begin(); err != nil {
+		return err
+	}
+
+	// search
+	err := j.initAndSearch(ctx)
+	j.finish(err)
+
+	return err
+}
+
+func (j *searchJob) initAndSearch(ctx context.Context) error {
+	// init pvr object
+	if err := j.pvr.Init(ctx); err != nil {
+		return errors.WithMessagef(err, "failed initializing pvr object for: %s", j.pvrName)
+	}
+
+	return j.search(ctx)
+}
+
+func (j *searchJob) begin() error {
+	// record the owner, so runs left running when it exits can be told apart from those in progress
+	host, _ := os.Hostname()
+
+	j.record = &database.SearchRun{
+		PvrName:    j.lowerPvrName,
+		WantedType: j.wantedType,
+		Status:     runStatusRunning,
+		StartedUtc: time.Now().UTC(),
+		Host:       host,
+		Pid:        os.Getpid(),
+	}
+
+	if err := j.store.AddSearchRun(j.record); err != nil {
+		return errors.WithMessage(err, "failed recording search run in database")
+	}
+
+	j.log = j.log.WithField("run_id", j.record.Id)
+
+	return nil
+}
+
+func (j *searchJob) finish(err error) {
+	// set final run status
+	endTime := time.Now().UTC()
+	j.record.EndedUtc = &endTime
+	j.stopMtx.Lock()
+	j.record.Reason = j.stopReason
+	j.stopMtx.Unlock()
+
+	switch {
+	case err != nil:
+		j.record.Status = runStatusFailed
+		j.record.Reason = err.Error()
+	case j.interrupted.Load():
+		j.record.Status = runStatusInterrupted
+	case j.record.Reason != "":
+		j.record.Status = runStatusAborted
+	default:
+		j.record.Status = runStatusCompleted
+	}
+
+	if err := j.store.UpdateSearchRun(j.record); err != nil {
+		j.log.WithError(err).Error("Failed recording search run in database")
+	}
+
+	j.log.WithFields(logrus.Fields{
+		"status":         j.record.Status,
+		"reason":         j.record.Reason,
+		"searched_items": j.record.SearchedItems,
+		"batches":        j.record.Batches,
+		"failed_batches": j.record.FailedBatches,
+	}).Info("Finished search run")
+
+	// send notifications
+	notify.Send(config.Get().Notifications, j.record)
+}
+
+func (j *searchJob) search(ctx context.Context) error {
+	// retrieve wanted records from pvr and stash in database
+	if err := j.refreshWanted(ctx); err != nil {
+		return err
+	}
+
+	// start queue monitor (checking once before searching)
+	monitorCtx, stopMonitor := context.WithCancel(ctx)
+	defer stopMonitor()
+
+	if j.maxQueueSize > 0 || j.pvrConfig.Throttle.Enabled() {
+		j.queueWaitExpires = time.Now().Add(j.queueWait)
+		if j.checkMonitors(ctx) {
+			go j.monitor(monitorCtx)
+		}
+	}
+
+	// get media items from database (future items are skipped when determining eligibility)
+	mediaItems, err := j.store.GetMediaItems(j.lowerPvrName, j.wantedType, false)
+	if err != nil {
+		return errors.WithMessage(err, "failed retrieving media items from database")
+	}
+	j.log.WithField("media_items", len(mediaItems)).Debug("Retrieved media items from database")
+
+	// determine media items eligible for search
+	eligibleItems := j.getEligibleItems(mediaItems)
+	j.log.WithField("eligible_items", len(eligibleItems)).Debug("Determined media items eligible for search")
+
+	metrics.CachedItems.WithLabelValues(j.lowerPvrName, j.wantedType).
+		Set(float64(j.store.GetItemsCount(j.lowerPvrName, j.wantedType)))
+	metrics.EligibleItems.WithLabelValues(j.lowerPvrName, j.wantedType).Set(float64(len(eligibleItems)))
+
+	// start searching
+	var searchItems []pvrObj.MediaItem
+	searchedItemsCount := 0
+
+	for _, item := range eligibleItems {
+		// abort if required (queue monitor and interrupts will set this)
+		if !j.waitForQueue(ctx) {
+			break
+		}
+
+		// add item to batch
+		searchItems = append(searchItems, pvrObj.MediaItem{
+			ItemId:     item.Id,
+			SeriesId:   item.SeriesId,
+			AirDateUtc: item.AirDateUtc,
+		})
+
+		// not enough items batched yet
+		batchedItemsCount := len(searchItems)
+		if batchedItemsCount < j.searchBatchSize {
+			continue
+		}
+
+		// do search
+		searchedItemsCount += batchedItemsCount
+		j.searchBatch(ctx, searchItems, searchedItemsCount)
+
+		// reset batch
+		searchItems = []pvrObj.MediaItem{}
+
+		// max search items reached?
+		if j.maxSearchItems > 0 && searchedItemsCount >= j.maxSearchItems {
+			j.log.WithField("searched_items", searchedItemsCount).
+				Info("Max search items reached, aborting...")
+			j.stop(stopReasonMaxSearch)
+			break
+		}
+
+		// sleep before next batch
+		select {
+		case <-ctx.Done():
+		case <-time.After(j.batchDelay):
+		}
+	}
+
+	// search for any leftover items from batching
+	if len(searchItems) > 0 && j.waitForQueue(ctx) {
+		searchedItemsCount += len(searchItems)
+		j.searchBatch(ctx, searchItems, searchedItemsCount)
+	}
+
+	// eligible items are searched in order, so any after those searched were left when the run stopped
+	for _, item := range eligibleItems[searchedItemsCount:] {
+		j.report.addSkipped(item, skipReasonRunStopped, nil)
+	}
+
+	return nil
+}
+
+func (j *searchJob) getEligibleItems(mediaItems []database.MediaItem) []database.MediaItem {
+	eligibleItems := make([]database.MediaItem, 0, len(mediaItems))
+	now := time.Now().UTC()
+
+	for _, item := range mediaItems {
+		// dont search this item if it has not aired yet
+		if j.excludeFuture && item.AirDateUtc.After(now) {
+			j.report.addSkipped(item, skipReasonFutureAirDate, nil)
+			continue
+		}
+
+		// dont search this item if we already searched it within N days
+		if item.LastSearchDateUtc != nil && !item.LastSearchDateUtc.IsZero() {
+			retryAfterDate := item.LastSearchDateUtc.Add(j.retryAge)
+			if now.Before(retryAfterDate) {
+				j.log.WithField("retry_min_date", retryAfterDate).
+					Tracef("Skipping media item %v until allowed retry date", item.Id)
+				j.report.addSkipped(item, skipReasonRetryAge, &retryAfterDate)
+				continue
+			}
+		}
+
+		eligibleItems = append(eligibleItems, item)
+	}
+
+	return eligibleItems
+}
+
+func (j *searchJob) refreshWanted(ctx context.Context) error {
+	existingItemsCount := j.store.GetItemsCount(j.lowerPvrName, j.wantedType)
+	if !j.refreshCache && existingItemsCount >= 1 {
+		return nil
+	}
+
+	j.log.Infof("Retrieving %s media from %s: %q", j.wantedDesc, capitalise.First(j.pvrConfig.Type), j.pvrName)
+
+	wantedRecords, err := j.getWanted(ctx)
+	if err != nil {
+		return errors.WithMessagef(err, "failed retrieving wanted %s pvr items", j.wantedDesc)
+	}
+
+	// stash wanted media in database
+	j.log.Debug("Stashing media items in database...")
+
+	if err := j.store.SetMediaItems(j.lowerPvrName, j.wantedType, wantedRecords); err != nil {
+		return errors.WithMessage(err, "failed stashing media items in database")
+	}
+
+	j.log.Info("Stashed media items")
+
+	// remove media no longer wanted
+	if existingItemsCount >= 1 {
+		j.log.Debugf("Removing media items from database that are no longer %s...", j.wantedDesc)
+
+		removedItems, err := j.store.DeleteMissingItems(j.lowerPvrName, j.wantedType, wantedRecords)
+		if err != nil {
+			return errors.WithMessagef(err, "failed removing media items from database that are no longer %s",
+				j.wantedDesc)
+		}
+
+		j.log.WithField("removed_items", removedItems).
+			Infof("Removed media items from database that are no longer %s", j.wantedDesc)
+	}
+
+	return nil
+}
+
+func (j *searchJob) monitor(ctx context.Context) {
+	j.log.Info("Started queue monitor")
+	for {
+		// sleep before check
+		select {
+		case <-ctx.Done():
+		case <-time.After(10 * time.Second):
+		}
+
+		if ctx.Err() != nil || !j.checkMonitors(ctx) {
+			break
+		}
+	}
+	j.log.Info("Finished queue monitor")
+}
+
+func (j *searchJob) checkMonitors(ctx context.Context) bool {
+	return j.checkQueue(ctx) && j.checkThrottle(ctx)
+}
+
+func (j *searchJob) checkQueue(ctx context.Context) bool {
+	if j.maxQueueSize <= 0 {
+		return true
+	}
+
+	// retrieve queue
+	queue, err := j.pvr.GetQueue(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			j.log.WithError(err).Error("Failed retrieving queue size, aborting...")
+			j.stop(stopReasonQueueError)
+		}
+		return false
+	}
+
+	for _, state := range pvrObj.QueueStates {
+		metrics.QueueItems.WithLabelValues(j.lowerPvrName, state).Set(float64(queue.States[state]))
+	}
+
+	// check queue size (of the targeted states)
+	qs := queue.StatesSize(j.queueStates)
+	j.log.WithFields(logrus.Fields{
+		"queue_size":   qs,
+		"queue_states": queue.States,
+	}).Trace("Queue size retrieved")
+
+	return j.checkQueueSize(qs)
+}
+
+func (j *searchJob) checkThrottle(ctx context.Context) bool {
+	throttle := j.pvrConfig.Throttle
+
+	// check free disk space of root folders
+	if throttle.MinFreeSpaceGb > 0 {
+		spaces, err := j.pvr.GetRootFolderSpace(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				j.log.WithError(err).Error("Failed retrieving root folder disk space, aborting...")
+				j.stop(stopReasonThrottleError)
+			}
+			return false
+		}
+
+		minFreeSpace := throttle.MinFreeSpaceGb * 1024 * 1024 * 1024
+		for _, space := range spaces {
+			if space.FreeSpace >= minFreeSpace {
+				continue
+			}
+
+			j.log.WithFields(logrus.Fields{
+				"root_folder":    space.Path,
+				"free_space_gb":  space.FreeSpace / 1024 / 1024 / 1024,
+				"min_free_space": throttle.MinFreeSpaceGb,
+			}).Warn("Root folder free disk space is below threshold, aborting...")
+			j.stop(stopReasonFreeSpace)
+			return false
+		}
+	}
+
+	// check download clients are enabled
+	if throttle.DownloadClients {
+		clients, err := j.pvr.GetDownloadClients(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				j.log.WithError(err).Error("Failed retrieving download clients, aborting...")
+				j.stop(stopReasonThrottleError)
+			}
+			return false
+		}
+
+		enabled := 0
+		for _, client := range clients {
+			if client.Enable {
+				enabled++
+			}
+		}
+
+		if enabled == 0 {
+			j.log.Warn("No download clients are enabled, aborting...")
+			j.stop(stopReasonDownloadClients)
+			return false
+		}
+	}
+
+	return true
+}
+
+func (j *searchJob) checkQueueSize(queueSize int) bool {
+	waitExpired := j.queueWait <= 0 || time.Now().After(j.queueWaitExpires)
+
+	switch {
+	case queueSize < j.queueResumeSize && j.searchPaused.Load():
+		// queue has drained below the low-water mark
+		j.log.WithField("queue_size", queueSize).Info("Queue has drained, resuming searches...")
+		j.searchPaused.Store(false)
+	case waitExpired && (queueSize >= j.maxQueueSize || j.searchPaused.Load()):
+		// queue is full (or has not drained while paused) and we are not allowed to wait any longer
+		j.log.WithFields(logrus.Fields{
+			"queue_size":  queueSize,
+			"resume_size": j.queueResumeSize,
+		}).Warn("Queue size has been reached, aborting....")
+		j.searchPaused.Store(false)
+		j.stop(stopReasonQueueFull)
+		return false
+	case queueSize >= j.maxQueueSize && !j.searchPaused.Load():
+		// queue is full, pause until it has drained
+		j.log.WithFields(logrus.Fields{
+			"queue_size":   queueSize,
+			"resume_size":  j.queueResumeSize,
+			"wait_expires": j.queueWaitExpires.Format(time.RFC3339),
+		}).Warn("Queue size has been reached, pausing searches...")
+		j.searchPaused.Store(true)
+	}
+
+	return true
+}
+
+func (j *searchJob) waitForQueue(ctx context.Context) bool {
+	// wait while searches are paused by the queue monitor
+	for j.searchPaused.Load() && j.continueRunning.Load() {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(1 * time.Second):
+		}
+	}
+
+	return j.continueRunning.Load()
+}
+
+func (j *searchJob) searchBatch(ctx context.Context, searchItems []pvrObj.MediaItem, searchedItemsCount int) {
+	j.record.Batches++
+	j.record.SearchedItems = searchedItemsCount
+	l := j.log.WithField("batch", j.record.Batches)
+
+	l.WithFields(logrus.Fields{
+		"search_items": len(searchItems),
+	}).Info("Searching...")
+
+	metrics.BatchesSent.WithLabelValues(j.lowerPvrName, j.wantedType).Inc()
+	defer func() {
+		if err := j.store.UpdateSearchRun(j.record); err != nil {
+			l.WithError(err).Error("Failed recording search run progress in database")
+		}
+	}()
+
+	if err := j.searchForItems(ctx, searchItems); err != nil {
+		l.WithError(err).Error("Failed searching for items...")
+		j.record.FailedBatches++
+
+		// abort if required (search command timeout policy)
+		if j.abortOnSearchError(err) {
+			l.Warn("Search command timed out, aborting...")
+			j.stop(stopReasonCommandTimeout)
+		}
+		return
+	}
+
+	metrics.ItemsSearched.WithLabelValues(j.lowerPvrName, j.wantedType).Add(float64(len(searchItems)))
+
+	l.WithFields(logrus.Fields{
+		"searched_items": searchedItemsCount,
+	}).Info("Search complete")
+}
+
+func (j *searchJob) searchForItems(ctx context.Context, searchItems []pvrObj.MediaItem) error {
+	// set variables required for search
+	searchItemIds := pluckMediaItemIds(searchItems)
+	searchTime := time.Now().UTC()
+
+	command, err := j.pvr.SearchMediaItems(ctx, searchItemIds)
+	j.report.addBatch(searchItemIds, command, searchTime, err)
+	if err != nil && command == nil {
+		metrics.CommandFailures.WithLabelValues(j.lowerPvrName, j.wantedType, "error").Inc()
+	}
+
+	// record search command
+	if command != nil {
+		metrics.CommandDuration.WithLabelValues(j.lowerPvrName, j.wantedType, command.Status).
+			Observe(command.Ended.Sub(command.Started).Seconds())
+
+		if err != nil {
+			metrics.CommandFailures.WithLabelValues(j.lowerPvrName, j.wantedType, command.Status).Inc()
+		}
+
+		if err := j.store.AddSearchHistory(j.record.Id, j.lowerPvrName, j.wantedType, len(searchItemIds),
+			command); err != nil {
+			j.log.WithError(err).Error("Failed recording search command in database")
+		}
+	}
+
+	// the pvr will carry on with abandoned and timed out search commands, so they are still recorded as searched
+	if err != nil && (command == nil || (command.Status != pvrObj.CommandStatusAbandoned &&
+		command.Status != pvrObj.CommandStatusTimedOut)) {
+		return err
+	}
+
+	// update search items lastsearch time
+	for pos := range searchItems {
+		(&searchItems[pos]).LastSearch = searchTime
+	}
+
+	if err := j.store.SetMediaItems(j.lowerPvrName, j.wantedType, searchItems); err != nil {
+		// searching again without recording the last search would repeat these items
+		j.stop(stopReasonDatabaseError)
+		return errors.WithMessage(err, "failed updating search items in database")
+	}
+
+	return err
+}
+
+func (j *searchJob) abortOnSearchError(err error) bool {
+	// abort when a search command timed out and the pvr timeout policy is to abort
+	return errors.Is(err, pvrObj.ErrCommandTimeout) &&
+		strings.EqualFold(j.pvrConfig.SearchCommand.OnTimeout, "abort")
+}
@@ -0,0 +1,374 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/l3uddz/wantarr/config"
+	"github.com/l3uddz/wantarr/database"
+	"github.com/l3uddz/wantarr/logger"
+	pvrObj "github.com/l3uddz/wantarr/pvr"
+	"go.uber.org/atomic"
+)
+
+/* Test Search Job */
+
+func TestRefreshWanted(t *testing.T) {
+	now := time.Now().UTC()
+	store := database.NewMemoryStore()
+
+	wanted := []pvrObj.MediaItem{
+		{ItemId: 1, AirDateUtc: now.Add(-48 * time.Hour)},
+		{ItemId: 2, AirDateUtc: now.Add(-24 * time.Hour)},
+		{ItemId: 3, AirDateUtc: now.Add(24 * time.Hour)},
+	}
+
+	job := &searchJob{
+		pvrName:      "Sonarr",
+		lowerPvrName: "sonarr",
+		pvrConfig:    &config.Pvr{Type: "sonarr_v3"},
+		store:        store,
+		log:          logger.GetLogger("test"),
+		wantedType:   "missing",
+		wantedDesc:   "missing",
+		retryAge:     24 * time.Hour,
+		getWanted: func(context.Context) ([]pvrObj.MediaItem, error) {
+			return wanted, nil
+		},
+		excludeFuture: true,
+	}
+
+	tests := []struct {
+		refreshCache     bool
+		wanted           []pvrObj.MediaItem
+		expectedItems    int
+		expectedEligible int
+	}{
+		// initial refresh stores all items, future items are not eligible
+		{false, wanted, 3, 2},
+		// cached items are used unless refreshing
+		{false, wanted[:1], 3, 2},
+		// items no longer wanted are removed
+		{true, wanted[1:], 2, 1},
+	}
+
+	for pos, tc := range tests {
+		job.refreshCache = tc.refreshCache
+		wanted = tc.wanted
+
+		if err := job.refreshWanted(context.Background()); err != nil {
+			t.Fatalf("Expected no error refreshing wanted items but got: %v", err)
+		}
+
+		mediaItems, err := store.GetMediaItems("sonarr", "missing", false)
+		if err != nil {
+			t.Fatalf("Expected no error getting media items but got: %v", err)
+		}
+
+		if len(mediaItems) != tc.expectedItems {
+			t.Errorf("Expected %d stored items for test %d but got: %d", tc.expectedItems, pos, len(mediaItems))
+		}
+
+		if eligible := job.getEligibleItems(mediaItems); len(eligible) != tc.expectedEligible {
+			t.Errorf("Expected %d eligible items for test %d but got: %d", tc.expectedEligible, pos, len(eligible))
+		}
+	}
+
+	// searched items are not eligible until the retry age has passed
+	if err := store.SetMediaItems("sonarr", "missing", []pvrObj.MediaItem{
+		{ItemId: 2, AirDateUtc: now.Add(-24 * time.Hour), LastSearch: now},
+	}); err != nil {
+		t.Fatalf("Expected no error updating media items but got: %v", err)
+	}
+
+	mediaItems, _ := store.GetMediaItems("sonarr", "missing", false)
+	if eligible := job.getEligibleItems(mediaItems); len(eligible) != 0 {
+		t.Errorf("Expected no eligible items after search but got: %+v", eligible)
+	}
+}
+
+/* Test Queue Size */
+
+func TestCheckQueueSize(t *testing.T) {
+	tests := []struct {
+		name            string
+		queueWait       time.Duration
+		waitExpired     bool
+		paused          bool
+		queueSize       int
+		expectedOk      bool
+		expectedPaused  bool
+		expectedStopped bool
+	}{
+		{"below max", 0, false, false, 40, true, false, false},
+		{"full without wait", 0, false, false, 50, false, false, true},
+		{"full with wait", time.Hour, false, false, 50, true, true, false},
+		{"paused not drained", time.Hour, false, true, 40, true, true, false},
+		{"paused drained", time.Hour, false, true, 20, true, false, false},
+		{"full wait expired", time.Hour, true, false, 60, false, false, true},
+		{"paused not drained wait expired", time.Hour, true, true, 40, false, false, true},
+		{"paused drained wait expired", time.Hour, true, true, 20, true, false, false},
+		{"below max wait expired", time.Hour, true, false, 40, true, false, false},
+	}
+
+	for _, tc := range tests {
+		job := &searchJob{
+			log:             logger.GetLogger("test"),
+			maxQueueSize:    50,
+			queueResumeSize: 25,
+			queueWait:       tc.queueWait,
+			continueRunning: atomic.NewBool(true),
+			searchPaused:    atomic.NewBool(tc.paused),
+		}
+
+		job.queueWaitExpires = time.Now().Add(tc.queueWait)
+		if tc.waitExpired {
+			job.queueWaitExpires = time.Now().Add(-time.Minute)
+		}
+
+		ok := job.checkQueueSize(tc.queueSize)
+		if ok != tc.expectedOk {
+			t.Errorf("Expected %v for %s but got: %v", tc.expectedOk, tc.name, ok)
+		}
+		if paused := job.searchPaused.Load(); paused != tc.expectedPaused {
+			t.Errorf("Expected paused %v for %s but got: %v", tc.expectedPaused, tc.name, paused)
+		}
+		if stopped := job.stopReason == stopReasonQueueFull; stopped != tc.expectedStopped {
+			t.Errorf("Expected stopped %v for %s but got reason: %q", tc.expectedStopped, tc.name, job.stopReason)
+		}
+	}
+}
+
+/* Test Search Batch */
+
+type testPvr struct {
+	pvrObj.Interface
+	init   func() error
+	search func([]int) (*pvrObj.SearchCommand, error)
+}
+
+func (p *testPvr) Init(context.Context) error {
+	if p.init == nil {
+		return nil
+	}
+	return p.init()
+}
+
+func (p *testPvr) SearchMediaItems(_ context.Context, ids []int) (*pvrObj.SearchCommand, error) {
+	return p.search(ids)
+}
+
+type failingStore struct {
+	*database.MemoryStore
+}
+
+func (s *failingStore) SetMediaItems(string, string, []pvrObj.MediaItem) error {
+	return errors.New("database is locked")
+}
+
+func newTestSearchJob(store database.Store, p pvrObj.Interface) *searchJob {
+	return &searchJob{
+		pvrName:         "Sonarr",
+		lowerPvrName:    "sonarr",
+		pvrConfig:       &config.Pvr{Type: "sonarr_v3"},
+		pvr:             p,
+		store:           store,
+		log:             logger.GetLogger("test"),
+		wantedType:      "missing",
+		wantedDesc:      "missing",
+		continueRunning: atomic.NewBool(true),
+		searchPaused:    atomic.NewBool(false),
+		interrupted:     atomic.NewBool(false),
+		record:          &database.SearchRun{PvrName: "sonarr", WantedType: "missing"},
+	}
+}
+
+func TestSearchBatchDatabaseError(t *testing.T) {
+	p := &testPvr{search: func(ids []int) (*pvrObj.SearchCommand, error) {
+		return &pvrObj.SearchCommand{Id: 1, Status: "completed"}, nil
+	}}
+
+	job := newTestSearchJob(&failingStore{database.NewMemoryStore()}, p)
+	if err := job.store.AddSearchRun(job.record); err != nil {
+		t.Fatal(err)
+	}
+
+	job.searchBatch(context.Background(), []pvrObj.MediaItem{{ItemId: 1}}, 1)
+
+	if job.record.FailedBatches != 1 {
+		t.Errorf("Expected 1 failed batch but got: %d", job.record.FailedBatches)
+	}
+	if job.continueRunning.Load() || job.stopReason != stopReasonDatabaseError {
+		t.Errorf("Expected run to stop with reason %q but got: %q", stopReasonDatabaseError, job.stopReason)
+	}
+}
+
+func TestSearchForItemsCommandStatus(t *testing.T) {
+	tests := []struct {
+		status           string
+		err              error
+		expectedSearched bool
+	}{
+		{"completed", nil, true},
+		{pvrObj.CommandStatusTimedOut, pvrObj.ErrCommandTimeout, true},
+		{pvrObj.CommandStatusAbandoned, context.Canceled, true},
+		// cancelled while retrieving the status of an accepted command
+		{pvrObj.CommandStatusAbandoned, fmt.Errorf("search abandoned while being monitored: %w", context.Canceled),
+			true},
+		{"aborted", pvrObj.ErrCommandAborted, false},
+		{"failed", errors.New("search failed"), false},
+		{"", errors.New("connection refused"), false},
+	}
+
+	for _, tc := range tests {
+		p := &testPvr{search: func(ids []int) (*pvrObj.SearchCommand, error) {
+			if tc.status == "" {
+				return nil, tc.err
+			}
+			return &pvrObj.SearchCommand{Id: 1, Status: tc.status}, tc.err
+		}}
+
+		store := database.NewMemoryStore()
+		job := newTestSearchJob(store, p)
+
+		if err := job.searchForItems(context.Background(), []pvrObj.MediaItem{{ItemId: 1}}); err != tc.err {
+			t.Errorf("Expected error %v for %q but got: %v", tc.err, tc.status, err)
+		}
+
+		mediaItems, _ := store.GetMediaItems("sonarr", "missing", false)
+		if searched := len(mediaItems) == 1 && mediaItems[0].LastSearchDateUtc != nil; searched != tc.expectedSearched {
+			t.Errorf("Expected searched %v for %q but got: %+v", tc.expectedSearched, tc.status, mediaItems)
+		}
+	}
+}
+
+func TestSearchReportsStoppedItems(t *testing.T) {
+	now := time.Now().UTC()
+	wanted := make([]pvrObj.MediaItem, 0, 5)
+	for id := 1; id <= 5; id++ {
+		wanted = append(wanted, pvrObj.MediaItem{ItemId: id, AirDateUtc: now.Add(-time.Duration(id) * time.Hour)})
+	}
+
+	p := &testPvr{search: func(ids []int) (*pvrObj.SearchCommand, error) {
+		return &pvrObj.SearchCommand{Id: ids[0], Status: "completed"}, nil
+	}}
+
+	job := newTestSearchJob(database.NewMemoryStore(), p)
+	job.getWanted = func(context.Context) ([]pvrObj.MediaItem, error) {
+		return wanted, nil
+	}
+	job.searchBatchSize = 2
+	job.maxSearchItems = 2
+	job.report, _ = newRunReport(reportFormatJson, searchOptions{})
+
+	if err := job.search(context.Background()); err != nil {
+		t.Fatalf("Expected no error searching but got: %v", err)
+	}
+
+	if job.stopReason != stopReasonMaxSearch || len(job.report.Batches) != 1 {
+		t.Fatalf("Expected 1 batch before stopping but got %d, reason: %q", len(job.report.Batches), job.stopReason)
+	}
+
+	var stopped []int
+	for _, item := range job.report.Skipped {
+		if item.Reason == skipReasonRunStopped {
+			stopped = append(stopped, item.Id)
+		}
+	}
+	if len(stopped) != 3 || stopped[0] != 3 || stopped[2] != 5 {
+		t.Errorf("Expected items 3, 4, 5 to be reported as not searched but got: %v", stopped)
+	}
+}
+
+func TestRunInitError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wantarr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// finishing a run sends notifications of the current config
+	configFile := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(configFile, []byte("pvr: {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Init(configFile); err != nil {
+		t.Fatal(err)
+	}
+
+	store := database.NewMemoryStore()
+	job := newTestSearchJob(store, &testPvr{init: func() error {
+		return errors.New("connection refused")
+	}})
+
+	if err := job.run(context.Background()); err == nil {
+		t.Fatal("Expected error running search with an unreachable pvr")
+	}
+
+	runs, _ := store.GetSearchRuns("sonarr", "missing", runStatusFailed, -1)
+	if len(runs) != 1 || runs[0].EndedUtc == nil || !strings.Contains(runs[0].Reason, "connection refused") {
+		t.Errorf("Expected failed run to be recorded but got: %+v", runs)
+	}
+}
+
+/* Test Stale Runs */
+
+func TestFinishStaleRuns(t *testing.T) {
+	log = logger.GetLogger("test")
+	store := database.NewMemoryStore()
+
+	host, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pid of a process that has exited
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Skipf("Failed running process: %v", err)
+	}
+	exitedPid := exited.Process.Pid
+
+	tests := []struct {
+		run                 database.SearchRun
+		expectedInterrupted bool
+	}{
+		{database.SearchRun{Status: runStatusRunning, Host: host, Pid: exitedPid}, true},
+		{database.SearchRun{Status: runStatusCompleted, Host: host, Pid: exitedPid}, false},
+		// in progress in this or another process of this host
+		{database.SearchRun{Status: runStatusRunning, Host: host, Pid: os.Getpid()}, false},
+		{database.SearchRun{Status: runStatusRunning, Host: host, Pid: os.Getppid()}, false},
+		// owned by another host sharing the database
+		{database.SearchRun{Status: runStatusRunning, Host: host + "-other", Pid: exitedPid}, false},
+		// recorded without an owner
+		{database.SearchRun{Status: runStatusRunning}, false},
+	}
+
+	for pos := range tests {
+		if err := store.AddSearchRun(&tests[pos].run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := finishStaleRuns(store); err != nil {
+		t.Fatalf("Expected no error finishing stale runs but got: %v", err)
+	}
+
+	runs, _ := store.GetSearchRuns("", "", "", -1)
+	for _, run := range runs {
+		tc := tests[run.Id-1]
+
+		interrupted := run.Status == runStatusInterrupted && run.EndedUtc != nil && run.Reason == stopReasonNotFinished
+		if interrupted != tc.expectedInterrupted {
+			t.Errorf("Expected interrupted %v for run %d but got: %+v", tc.expectedInterrupted, run.Id, run)
+		}
+	}
+}
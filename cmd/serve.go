@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/l3uddz/wantarr/config"
+	"github.com/l3uddz/wantarr/database"
+	"github.com/l3uddz/wantarr/metrics"
+	"github.com/l3uddz/wantarr/server"
+	"github.com/l3uddz/wantarr/utils/process"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	flagServeBind        = "127.0.0.1:8181"
+	flagServeApiKey      = ""
+	flagServeWatchConfig = false
+
+	errRunInProgress = errors.New("search run already in progress")
+)
+
+/* Structs */
+
+type runManager struct {
+	ctx   context.Context
+	store database.Store
+	jobs  map[string]*searchJob
+	mtx   sync.Mutex
+	wg    sync.WaitGroup
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Start the http api server",
+	Long:  `This command can be used to start a http server exposing status and allowing searches to be triggered.`,
+
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// load database
+		store, err := database.Open(flagDatabaseFile, config.Get().Database.DSN, true)
+		if err != nil {
+			log.WithError(err).Fatal("Failed opening database")
+		}
+
+		// runs left running by processes that have exited will never finish
+		if err := finishStaleRuns(store); err != nil {
+			log.WithError(err).Error("Failed marking unfinished search runs as interrupted")
+		}
+
+		// stop on interrupt
+		stop := make(chan struct{})
+		ctx, cancel := notifyInterrupt(func() {
+			close(stop)
+		})
+		defer cancel()
+
+		// init server
+		runs := newRunManager(ctx, store)
+		srv := server.New(flagServeBind, flagServeApiKey)
+		registerApiRoutes(srv, store, runs)
+		srv.Handle(http.MethodGet, "/metrics", metrics.Handler().ServeHTTP)
+
+		// set initial cache metrics
+		setCachedItemsMetrics(store, config.Get())
+
+		// reload config on change / hangup (in-progress runs keep the config they started with)
+		if flagServeWatchConfig {
+			if err := config.Watch(ctx, func(cfg *config.Configuration) {
+				setCachedItemsMetrics(store, cfg)
+			}); err != nil {
+				log.WithError(err).Error("Failed watching config file for changes")
+			}
+		}
+
+		go reloadOnHangup(ctx, store)
+
+		go func() {
+			if err := srv.Start(); err != nil {
+				log.WithError(err).Fatal("Failed starting http server")
+			}
+		}()
+
+		<-stop
+
+		// stop accepting requests
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer shutdownCancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.WithError(err).Error("Failed shutting down http server gracefully...")
+		}
+
+		// wait for in-progress runs to finish their current batch
+		runs.interrupt()
+		runs.wait()
+
+		closeStore(store)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVarP(&flagServeBind, "bind", "b", flagServeBind, "Address to bind the http server to.")
+	serveCmd.Flags().StringVar(&flagServeApiKey, "api-key", flagServeApiKey, "Api key required for http requests.")
+	serveCmd.Flags().BoolVar(&flagServeWatchConfig, "watch-config", flagServeWatchConfig, "Reload the config when the config file changes.")
+}
+
+/* Private */
+
+func reloadOnHangup(ctx context.Context, store database.Store) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigs:
+			log.Info("Received hangup, reloading config...")
+
+			cfg, err := config.Reload()
+			if err != nil {
+				log.WithError(err).Error("Failed reloading config, keeping current config")
+				continue
+			}
+
+			setCachedItemsMetrics(store, cfg)
+		}
+	}
+}
+
+// finishStaleRuns marks runs left running by processes of this host that have exited as interrupted, runs of other
+// hosts sharing the database are left alone as they may still be in progress
+func finishStaleRuns(store database.Store) error {
+	host, err := os.Hostname()
+	if err != nil {
+		return errors.Wrap(err, "failed determining hostname")
+	}
+
+	runs, err := store.GetSearchRuns("", "", runStatusRunning, -1)
+	if err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		run := run
+		if run.Host != host || run.Pid == 0 || run.Pid == os.Getpid() || process.Running(run.Pid) {
+			continue
+		}
+
+		endTime := time.Now().UTC()
+
+		run.Status = runStatusInterrupted
+		run.Reason = stopReasonNotFinished
+		run.EndedUtc = &endTime
+
+		if err := store.UpdateSearchRun(&run); err != nil {
+			return err
+		}
+
+		log.WithFields(logrus.Fields{
+			"pvr":         run.PvrName,
+			"wanted_type": run.WantedType,
+			"run_id":      run.Id,
+		}).Warn("Marked unfinished search run as interrupted")
+	}
+
+	return nil
+}
+
+func setCachedItemsMetrics(store database.Store, cfg *config.Configuration) {
+	for name := range cfg.Pvr {
+		for _, wantedType := range apiWantedTypes {
+			metrics.CachedItems.WithLabelValues(strings.ToLower(name), wantedType).
+				Set(float64(store.GetItemsCount(strings.ToLower(name), wantedType)))
+		}
+	}
+}
+
+func newRunManager(ctx context.Context, store database.Store) *runManager {
+	return &runManager{
+		ctx:   ctx,
+		store: store,
+		jobs:  make(map[string]*searchJob),
+	}
+}
+
+func (m *runManager) start(pvrName string, wantedType string, opts searchOptions) (*database.SearchRun, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	// validate no run is in progress for this pvr / wanted type
+	key := fmt.Sprintf("%s/%s", pvrName, wantedType)
+	if _, ok := m.jobs[key]; ok {
+		return nil, errRunInProgress
+	}
+
+	job, err := newSearchJob(m.store, pvrName, wantedType, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// record run
+	if err := job.begin(); err != nil {
+		return nil, err
+	}
+
+	m.jobs[key] = job
+	m.wg.Add(1)
+
+	run := *job.record
+
+	go func() {
+		defer m.wg.Done()
+
+		job.log.Infof("Started %s search run for: %s", job.wantedDesc, pvrName)
+
+		// init pvr object and search
+		err := job.initAndSearch(m.ctx)
+		if err != nil {
+			job.log.WithError(err).Errorf("Failed searching for %s media", job.wantedDesc)
+		}
+
+		job.finish(err)
+
+		m.mtx.Lock()
+		delete(m.jobs, key)
+		m.mtx.Unlock()
+	}()
+
+	return &run, nil
+}
+
+func (m *runManager) interrupt() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	for _, job := range m.jobs {
+		job.interrupt()
+	}
+}
+
+func (m *runManager) wait() {
+	m.wg.Wait()
+}
@@ -1,34 +1,73 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"github.com/json-iterator/go"
 	"os"
+	"path/filepath"
+	"sync"
 
 	"github.com/l3uddz/wantarr/logger"
 	stringutils "github.com/l3uddz/wantarr/utils/strings"
 
+	"github.com/fsnotify/fsnotify"
+	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 )
 
 type Configuration struct {
-	Pvr map[string]*Pvr
+	Pvr           map[string]*Pvr
+	Database      Database
+	Notifications []Notification
+	Logging       Logging
+}
+
+// LoadErrors are the errors found decoding a config file and resolving its secrets, by section
+type LoadErrors struct {
+	Config   []error
+	Database []error
+	Pvr      map[string][]error
+}
+
+type configDefault struct {
+	key   string
+	value interface{}
 }
 
 /* Vars */
 
 var (
-	// Config exports the config object
-	Config *Configuration
 	// Internal
-	log          = logger.GetLogger("cfg")
-	json         = jsoniter.ConfigCompatibleWithStandardLibrary
-	newOptionLen = 0
+	cfg            *Configuration
+	cfgMtx         sync.RWMutex
+	reloadMtx      sync.Mutex
+	log            = logger.GetLogger("cfg")
+	json           = jsoniter.ConfigCompatibleWithStandardLibrary
+	configDefaults = []configDefault{
+		// pvr settings
+		{"pvr", map[string]Pvr{}},
+
+		// logging settings
+		{"logging.format", "text"},
+		{"logging.console", "stderr"},
+		{"logging.file.max_size", 5},
+		{"logging.file.max_backups", 10},
+		{"logging.file.max_age", 90},
+	}
 )
 
 /* Public */
 
+// Get returns the current config, which is replaced rather than modified when reloaded
+func Get() *Configuration {
+	cfgMtx.RLock()
+	defer cfgMtx.RUnlock()
+
+	return cfg
+}
+
 func (cfg Configuration) ToJsonString() (string, error) {
 	c := viper.AllSettings()
 	bs, err := json.MarshalIndent(c, "", "  ")
@@ -46,85 +85,293 @@ func Init(configFilePath string) error {
 	// read matching env vars
 	viper.AutomaticEnv()
 
+	// Set defaults (in memory, the config file is only written by config migrate)
+	setConfigDefaults()
+
 	// Load config
 	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok || os.IsNotExist(err) {
-			// set the default config to be written
-			if err := setConfigDefaults(false); err != nil {
-				log.WithError(err).Error("Failed to add config defaults")
-				return errors.Wrap(err, "failed adding config defaults")
-			}
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok && !os.IsNotExist(err) {
+			log.WithError(err).Error("Configuration read error")
+			return errors.Wrap(err, "failed reading config")
+		}
 
-			// write default config
-			if err := viper.WriteConfig(); err != nil {
-				log.WithError(err).Fatalf("Failed dumping default configuration to %q", configFilePath)
-			}
+		log.Warnf("Configuration file was not found, using defaults. Create it with: wantarr config migrate --write")
+	} else {
+		// check whether new options were added
+		missing, err := missingConfigDefaults(configFilePath)
+		if err != nil {
+			log.WithError(err).Error("Failed checking for new config options")
+			return errors.WithMessage(err, "failed checking for new config options")
+		}
+
+		showNewConfigOptions(missing)
+	}
+
+	// Unmarshal into Config struct
+	c, err := decode()
+	if err != nil {
+		return err
+	}
+
+	set(c)
+	return nil
+}
+
+// Load reads a config file without applying it, collecting the errors of each section rather than failing on the
+// first, so they can all be reported.
+func Load(configFilePath string) (*Configuration, *LoadErrors, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	v.SetConfigFile(configFilePath)
+	v.AutomaticEnv()
 
-			log.Infof("Dumped default configuration to %q. Please edit before running again!",
-				viper.ConfigFileUsed())
-			log.Logger.Exit(0)
+	for _, d := range configDefaults {
+		v.SetDefault(d.key, d.value)
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, nil, errors.Wrap(err, "failed reading config")
+	}
+
+	c := &Configuration{Pvr: make(map[string]*Pvr)}
+	loadErrs := &LoadErrors{Pvr: make(map[string][]error)}
+
+	// decode pvrs separately so their errors are reported per pvr
+	settings := v.AllSettings()
+	pvrSettings, _ := settings["pvr"].(map[string]interface{})
+	delete(settings, "pvr")
+
+	loadErrs.Config = decodeErrors(decodeSettings(settings, c))
+
+	for name, ps := range pvrSettings {
+		pvr := new(Pvr)
+		errs := decodeErrors(decodeSettings(ps, pvr))
+
+		if err := pvr.resolveSecrets(name); err != nil {
+			errs = append(errs, err)
+		}
+		pvr.splitApiPath()
+
+		c.Pvr[name] = pvr
+		if len(errs) > 0 {
+			loadErrs.Pvr[name] = errs
 		}
+	}
 
+	if err := c.Database.resolveSecrets(); err != nil {
+		loadErrs.Database = append(loadErrs.Database, err)
+	}
+
+	return c, loadErrs, nil
+}
+
+// Reload re-reads the config file, keeping the current config when it is invalid
+func Reload() (*Configuration, error) {
+	reloadMtx.Lock()
+	defer reloadMtx.Unlock()
+
+	if err := viper.ReadInConfig(); err != nil {
 		log.WithError(err).Error("Configuration read error")
-		return errors.Wrap(err, "failed reading config")
+		return nil, errors.Wrap(err, "failed reading config")
 	}
 
-	// Set defaults (checking whether new options were added)
-	if err := setConfigDefaults(true); err != nil {
-		log.WithError(err).Error("Failed to add new config defaults")
-		return errors.Wrap(err, "failed adding new config defaults")
+	c, err := decode()
+	if err != nil {
+		return nil, err
 	}
 
-	// Unmarshal into Config struct
-	if err := viper.Unmarshal(&Config); err != nil {
-		log.WithError(err).Error("Configuration decode error")
-		return errors.Wrap(err, "failed decoding config")
+	set(c)
+	log.Info("Reloaded configuration")
+	return c, nil
+}
+
+// Watch reloads the config whenever the config file changes until the context is done, reloads are serialized with
+// those of Reload.
+func Watch(ctx context.Context, onReload func(*Configuration)) error {
+	configFile := filepath.Clean(viper.ConfigFileUsed())
+	configDir := filepath.Dir(configFile)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "failed creating config file watcher")
 	}
 
+	// watch the folder, as editors and kubernetes config maps replace the file rather than write to it
+	if err := watcher.Add(configDir); err != nil {
+		_ = watcher.Close()
+		return errors.Wrapf(err, "failed watching config folder: %q", configDir)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		realConfigFile, _ := filepath.EvalSymlinks(configFile)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.WithError(err).Error("Failed watching config file")
+			case e, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				// reload when the file is written / created, or the file it links to has been replaced
+				currentConfigFile, _ := filepath.EvalSymlinks(configFile)
+				written := filepath.Clean(e.Name) == configFile && e.Op&(fsnotify.Write|fsnotify.Create) != 0
+				relinked := currentConfigFile != "" && currentConfigFile != realConfigFile
+				if !written && !relinked {
+					continue
+				}
+				realConfigFile = currentConfigFile
+
+				log.WithField("op", e.Op.String()).Debugf("Configuration file changed: %q", e.Name)
+
+				c, err := Reload()
+				if err != nil {
+					log.WithError(err).Error("Failed reloading configuration, keeping current configuration")
+					continue
+				}
+
+				if onReload != nil {
+					onReload(c)
+				}
+			}
+		}
+	}()
+
 	return nil
 }
 
 /* Private */
 
-func setConfigDefault(key string, value interface{}, check bool) int {
-	if check {
-		if viper.IsSet(key) {
-			return 0
+func set(c *Configuration) {
+	cfgMtx.Lock()
+	cfg = c
+	cfgMtx.Unlock()
+}
+
+func decode() (*Configuration, error) {
+	c := new(Configuration)
+
+	if err := viper.Unmarshal(c, viper.DecodeHook(decodeHook())); err != nil {
+		log.WithError(err).Error("Configuration decode error")
+		return nil, errors.Wrap(err, "failed decoding config")
+	}
+
+	// resolve database secrets
+	if err := c.Database.resolveSecrets(); err != nil {
+		log.WithError(err).Error("Configuration secrets error for database")
+		return nil, errors.WithMessage(err, "failed resolving secrets for database")
+	}
+
+	// resolve pvr secrets
+	for name, pvr := range c.Pvr {
+		if err := pvr.resolveSecrets(name); err != nil {
+			log.WithError(err).Errorf("Configuration secrets error for pvr: %s", name)
+			return nil, errors.WithMessagef(err, "failed resolving secrets for pvr: %s", name)
 		}
 
-		// determine padding to use for new key
-		if keyLen := len(key); (keyLen + 2) > newOptionLen {
-			newOptionLen = keyLen + 2
+		if pvr.splitApiPath() {
+			log.Warnf("The url of pvr %s includes the api path, use url: %q and api_path: %q instead", name,
+				pvr.URL, pvr.APIPath)
 		}
+	}
 
-		log.Warnf("New config option: %s = %v", stringutils.StringLeftJust(fmt.Sprintf("%q", key),
-			" ", newOptionLen), value)
+	return c, nil
+}
+
+func decodeHook() mapstructure.DecodeHookFunc {
+	return mapstructure.ComposeDecodeHookFunc(
+		retryAgeHookFunc(),
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	)
+}
+
+// decodeSettings decodes settings the same way as viper.Unmarshal
+func decodeSettings(settings interface{}, result interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       decodeHook(),
+		WeaklyTypedInput: true,
+		Result:           result,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed creating config decoder")
+	}
+
+	return decoder.Decode(settings)
+}
+
+// decodeErrors splits a decode error into an error per setting
+func decodeErrors(err error) []error {
+	if err == nil {
+		return nil
 	}
 
-	viper.SetDefault(key, value)
+	var decodeErr *mapstructure.Error
+	if !errors.As(err, &decodeErr) {
+		return []error{err}
+	}
 
-	return 1
+	errs := make([]error, 0, len(decodeErr.Errors))
+	for _, msg := range decodeErr.Errors {
+		errs = append(errs, errors.New(msg))
+	}
+
+	return errs
+}
+
+func setConfigDefaults() {
+	for _, d := range configDefaults {
+		viper.SetDefault(d.key, d.value)
+	}
 }
 
-func setConfigDefaults(check bool) error {
-	added := 0
+func missingConfigDefaults(configFilePath string) ([]configDefault, error) {
+	// read config file without defaults or env vars
+	v := viper.New()
+	v.SetConfigType("yaml")
+	v.SetConfigFile(configFilePath)
 
-	// pvr settings
-	added += setConfigDefault("pvr", map[string]Pvr{}, check)
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok && !os.IsNotExist(err) {
+			return nil, errors.Wrap(err, "failed reading config")
+		}
+	}
+
+	var missing []configDefault
+	for _, d := range configDefaults {
+		if !v.IsSet(d.key) {
+			missing = append(missing, d)
+		}
+	}
 
-	// core settings
-	//added += setConfigDefault("core.workers", 8, check)
+	return missing, nil
+}
 
-	// were new settings added?
-	if check && added > 0 {
-		if err := viper.WriteConfig(); err != nil {
-			log.WithError(err).Error("Failed saving configuration with new options...")
-			return errors.Wrap(err, "failed saving updated configuration")
+func showNewConfigOptions(missing []configDefault) {
+	if len(missing) == 0 {
+		return
+	}
+
+	// determine padding to use for new keys
+	newOptionLen := 0
+	for _, d := range missing {
+		if keyLen := len(d.key); (keyLen + 2) > newOptionLen {
+			newOptionLen = keyLen + 2
 		}
+	}
 
-		log.Info("Configuration was saved with new options!")
-		log.Logger.Exit(0)
+	for _, d := range missing {
+		log.Warnf("New config option: %s = %v", stringutils.StringLeftJust(fmt.Sprintf("%q", d.key),
+			" ", newOptionLen), d.value)
 	}
 
-	return nil
+	log.Info("Using defaults for new config options. Add them to the config file with: wantarr config migrate")
 }
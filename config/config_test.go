@@ -0,0 +1,113 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+/* Test Load */
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wantarr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(configFile, []byte(`pvr:
+  sonarr:
+    type: sonarr_v3
+    api_key: ${WANTARR_TEST_UNSET_KEY}
+    retry_days_age:
+      missing: 1.5
+  radarr:
+    type: radarr_v3
+    api_key_file: `+filepath.Join(dir, "missing")+`
+  lidarr:
+    type: radarr_v3
+    api_key: key
+    http:
+      retries: 0
+logging:
+  file:
+    max_size: large
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, loadErrs, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("Expected no error loading config but got: %v", err)
+	}
+
+	if len(c.Pvr) != 3 || c.Pvr["lidarr"].ApiKey != "key" {
+		t.Errorf("Expected 3 pvrs but got: %+v", c.Pvr)
+	}
+	if retries := c.Pvr["lidarr"].HTTP.Retries; retries == nil || *retries != 0 {
+		t.Errorf("Expected retries to be disabled but got: %v", retries)
+	}
+	if len(loadErrs.Config) != 1 {
+		t.Errorf("Expected 1 config error but got: %v", loadErrs.Config)
+	}
+
+	for name, expected := range map[string]int{"sonarr": 2, "radarr": 1, "lidarr": 0} {
+		if errs := loadErrs.Pvr[name]; len(errs) != expected {
+			t.Errorf("Expected %d errors for pvr %s but got: %v", expected, name, errs)
+		}
+	}
+}
+
+/* Test Watch */
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wantarr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(configFile, []byte("pvr: {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Init(configFile); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloaded := make(chan *Configuration, 10)
+	if err := Watch(ctx, func(c *Configuration) {
+		reloaded <- c
+	}); err != nil {
+		t.Fatalf("Expected no error watching config but got: %v", err)
+	}
+
+	// reloads from changes and hangups are serialized
+	go func() {
+		_, _ = Reload()
+	}()
+
+	if err := ioutil.WriteFile(configFile, []byte("pvr:\n  sonarr:\n    type: sonarr_v3\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the file may be reloaded while only partly written
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case c := <-reloaded:
+			if _, ok := c.Pvr["sonarr"]; ok {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("Expected config to be reloaded after the config file changed but got: %+v", Get().Pvr)
+		}
+	}
+}
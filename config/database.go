@@ -0,0 +1,5 @@
+package config
+
+type Database struct {
+	DSN string
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+var (
+	retryAgePartRegex = regexp.MustCompile(`(\d+)(ms|s|m|h|d|w)`)
+	retryAgeType      = reflect.TypeOf(RetryAge(0))
+)
+
+/* Structs */
+
+// RetryAge is a duration where bare integers are days, e.g. 90, 12h, 3d, 2w or 1d12h
+type RetryAge time.Duration
+
+/* Public */
+
+func (a RetryAge) Duration() time.Duration {
+	return time.Duration(a)
+}
+
+func (a RetryAge) String() string {
+	return time.Duration(a).String()
+}
+
+func ParseRetryAge(value string) (RetryAge, error) {
+	value = strings.TrimSpace(value)
+
+	// bare integers are days
+	if days, err := strconv.Atoi(value); err == nil {
+		if days < 0 {
+			return 0, fmt.Errorf("retry age must not be negative: %q", value)
+		}
+		return RetryAge(time.Duration(days) * 24 * time.Hour), nil
+	}
+
+	// durations must be made up of whole numbers with units, e.g. 1d12h
+	parts := retryAgePartRegex.FindAllStringSubmatch(value, -1)
+	if value == "" || len(parts) == 0 || strings.Join(retryAgePartRegex.FindAllString(value, -1), "") != value {
+		return 0, fmt.Errorf("invalid retry age: %q (expected days or a duration, e.g. 90, 12h, 3d or 2w)",
+			value)
+	}
+
+	var d time.Duration
+	for _, part := range parts {
+		n, err := strconv.Atoi(part[1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid retry age: %q", value)
+		}
+
+		switch part[2] {
+		case "w":
+			d += time.Duration(n) * 7 * 24 * time.Hour
+		case "d":
+			d += time.Duration(n) * 24 * time.Hour
+		default:
+			pd, err := time.ParseDuration(part[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid retry age: %q", value)
+			}
+			d += pd
+		}
+	}
+
+	return RetryAge(d), nil
+}
+
+/* Private */
+
+func retryAgeHookFunc() mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if t != retryAgeType {
+			return data, nil
+		}
+
+		switch v := data.(type) {
+		case string:
+			return ParseRetryAge(v)
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return ParseRetryAge(fmt.Sprintf("%d", v))
+		default:
+			// floats are ambiguous, e.g. 1.5 days
+			return nil, fmt.Errorf("invalid retry age: %v (expected days or a duration, e.g. 90, 12h, 3d or 2w)",
+				data)
+		}
+	}
+}
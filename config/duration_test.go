@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+/* Test Retry Age */
+
+func TestParseRetryAge(t *testing.T) {
+	day := 24 * time.Hour
+
+	tests := []struct {
+		value       string
+		expected    time.Duration
+		expectError bool
+	}{
+		{"90", 90 * day, false},
+		{"0", 0, false},
+		{"12h", 12 * time.Hour, false},
+		{"30m", 30 * time.Minute, false},
+		{"3d", 3 * day, false},
+		{"2w", 14 * day, false},
+		{"1d12h", day + 12*time.Hour, false},
+		{" 7d ", 7 * day, false},
+		{"1.5", 0, true},
+		{"1.5d", 0, true},
+		{"-1", 0, true},
+		{"-1d", 0, true},
+		{"3 days", 0, true},
+		{"1y", 0, true},
+		{"d", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tc := range tests {
+		age, err := ParseRetryAge(tc.value)
+		if tc.expectError {
+			if err == nil {
+				t.Errorf("Expected error for %q but got: %s", tc.value, age)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tc.value, err)
+			continue
+		}
+
+		if age.Duration() != tc.expected {
+			t.Errorf("Expected %s for %q but got: %s", tc.expected, tc.value, age)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package config
+
+type Logging struct {
+	Format     string
+	FileFormat string `mapstructure:"file_format"`
+	Console    string
+	File       LoggingFile
+	Syslog     LoggingSyslog
+}
+
+type LoggingFile struct {
+	Disabled   bool
+	MaxSize    int  `mapstructure:"max_size"`
+	MaxBackups int  `mapstructure:"max_backups"`
+	MaxAge     int  `mapstructure:"max_age"`
+	PerPvr     bool `mapstructure:"per_pvr"`
+}
+
+type LoggingSyslog struct {
+	Enabled bool
+	Network string
+	Address string
+	Tag     string
+}
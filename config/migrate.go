@@ -0,0 +1,249 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+/* Structs */
+
+type Migration struct {
+	// Original is the config file as it is now
+	Original []byte
+	// Updated is the config file with the new options added
+	Updated []byte
+	// Added lists the new options
+	Added []string
+	// Reformatted is set when the new options could not be inserted into the original text, so the config was
+	// re-encoded without its comments and formatting
+	Reformatted bool
+}
+
+/* Vars */
+
+var configKeyRegex = regexp.MustCompile(`^("[^"]*"|'[^']*'|[^\s#'"{\[\-][^:#]*?)\s*:(?:\s+(.*))?$`)
+
+/* Public */
+
+// Migrate determines the config file with any new options added, which are inserted into the original text so
+// comments and formatting are preserved.
+func Migrate(configFilePath string) (*Migration, error) {
+	// read config file
+	data, err := ioutil.ReadFile(configFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "failed reading config file: %q", configFilePath)
+	}
+
+	var settings yaml.MapSlice
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, errors.Wrapf(err, "failed decoding config file: %q", configFilePath)
+	}
+
+	// add new options
+	missing, err := missingConfigDefaults(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Migration{Original: data}
+	for _, d := range missing {
+		m.Added = append(m.Added, d.key)
+	}
+
+	if updated, ok := insertConfigDefaults(data, missing); ok {
+		m.Updated = updated
+		return m, nil
+	}
+
+	// fallback to re-encoding the config
+	for _, d := range missing {
+		settings = setMapSliceValue(settings, strings.Split(d.key, "."), d.value)
+	}
+
+	if m.Updated, err = yaml.Marshal(settings); err != nil {
+		return nil, errors.Wrap(err, "failed encoding config")
+	}
+
+	m.Reformatted = true
+	return m, nil
+}
+
+/* Private */
+
+func insertConfigDefaults(data []byte, missing []configDefault) ([]byte, bool) {
+	if len(missing) == 0 {
+		return data, true
+	}
+
+	text := string(data)
+	if strings.Contains(text, "\t") || strings.Contains(text, "\r") {
+		return nil, false
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if text == "" {
+		lines = nil
+	}
+
+	// insert each option into its parent block
+	for _, d := range missing {
+		var ok bool
+		if lines, ok = insertConfigKey(lines, 0, len(lines), 0, strings.Split(d.key, "."), d.value); !ok {
+			return nil, false
+		}
+	}
+
+	updated := []byte(strings.Join(lines, "\n") + "\n")
+
+	// ensure the result decodes with the new options set
+	var settings yaml.MapSlice
+	if err := yaml.Unmarshal(updated, &settings); err != nil {
+		return nil, false
+	}
+
+	for _, d := range missing {
+		if !hasMapSliceValue(settings, strings.Split(d.key, ".")) {
+			return nil, false
+		}
+	}
+
+	return updated, true
+}
+
+// insertConfigKey inserts path into the block mapping of lines[start:end], whose keys are indented by the indent of
+// its first key, or defaultIndent when the block is empty.
+func insertConfigKey(lines []string, start int, end int, defaultIndent int, path []string,
+	value interface{}) ([]string, bool) {
+	indent := -1
+	last := start - 1
+
+	for pos := start; pos < end; pos++ {
+		lineIndent, content := splitConfigLine(lines[pos])
+		if content == "" {
+			continue
+		}
+
+		if indent < 0 {
+			indent = lineIndent
+		}
+
+		last = pos
+		if lineIndent > indent {
+			// belongs to a child block
+			continue
+		} else if lineIndent < indent {
+			return nil, false
+		}
+
+		match := configKeyRegex.FindStringSubmatch(content)
+		if match == nil {
+			return nil, false
+		}
+
+		if !strings.EqualFold(strings.Trim(match[1], `"'`), path[0]) {
+			continue
+		}
+
+		if len(path) == 1 {
+			return lines, true
+		}
+
+		// descend into existing mapping, which must be a block
+		if rest := strings.TrimSpace(match[2]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return nil, false
+		}
+
+		childEnd := pos + 1
+		for childPos := pos + 1; childPos < end; childPos++ {
+			childIndent, childContent := splitConfigLine(lines[childPos])
+			if childContent == "" {
+				continue
+			}
+			if childIndent <= indent {
+				break
+			}
+			childEnd = childPos + 1
+		}
+
+		return insertConfigKey(lines, pos+1, childEnd, indent+2, path[1:], value)
+	}
+
+	if indent < 0 {
+		indent = defaultIndent
+	}
+
+	// render new key
+	encoded, err := yaml.Marshal(setMapSliceValue(nil, path, value))
+	if err != nil {
+		return nil, false
+	}
+
+	var inserted []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(encoded), "\n"), "\n") {
+		inserted = append(inserted, strings.Repeat(" ", indent)+line)
+	}
+
+	// insert after the last line of the block
+	result := make([]string, 0, len(lines)+len(inserted))
+	result = append(result, lines[:last+1]...)
+	result = append(result, inserted...)
+	result = append(result, lines[last+1:]...)
+
+	return result, true
+}
+
+// splitConfigLine returns the indent and content of a line, content is empty for blank, comment and document lines.
+func splitConfigLine(line string) (int, string) {
+	content := strings.TrimLeft(line, " ")
+	if content == "" || strings.HasPrefix(content, "#") || content == "---" || content == "..." {
+		return 0, ""
+	}
+
+	return len(line) - len(content), strings.TrimRight(content, " ")
+}
+
+func hasMapSliceValue(ms yaml.MapSlice, path []string) bool {
+	for _, item := range ms {
+		if key, ok := item.Key.(string); !ok || !strings.EqualFold(key, path[0]) {
+			continue
+		}
+
+		if len(path) == 1 {
+			return true
+		}
+
+		child, ok := item.Value.(yaml.MapSlice)
+		return ok && hasMapSliceValue(child, path[1:])
+	}
+
+	return false
+}
+
+func setMapSliceValue(ms yaml.MapSlice, path []string, value interface{}) yaml.MapSlice {
+	for pos, item := range ms {
+		if key, ok := item.Key.(string); !ok || !strings.EqualFold(key, path[0]) {
+			continue
+		}
+
+		if len(path) == 1 {
+			return ms
+		}
+
+		// descend into existing mapping
+		child, _ := item.Value.(yaml.MapSlice)
+		ms[pos].Value = setMapSliceValue(child, path[1:], value)
+		return ms
+	}
+
+	// add new key
+	if len(path) == 1 {
+		return append(ms, yaml.MapItem{Key: path[0], Value: value})
+	}
+
+	return append(ms, yaml.MapItem{Key: path[0], Value: setMapSliceValue(nil, path[1:], value)})
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+)
+
+/* Test Insert Config Defaults */
+
+func TestInsertConfigDefaults(t *testing.T) {
+	missing := []configDefault{
+		{"logging.format", "text"},
+		{"logging.file.max_age", 90},
+	}
+
+	tests := []struct {
+		name     string
+		config   string
+		expected string
+		ok       bool
+	}{
+		{"empty", "", "logging:\n  format: text\n  file:\n    max_age: 90\n", true},
+		{
+			"comments preserved",
+			"# wantarr config\npvr:\n  sonarr:\n    url: http://localhost:8989 # local\n\n# logging\nlogging:\n" +
+				"    console: stdout\n    file:\n        max_size: 5\n",
+			"# wantarr config\npvr:\n  sonarr:\n    url: http://localhost:8989 # local\n\n# logging\nlogging:\n" +
+				"    console: stdout\n    file:\n        max_size: 5\n        max_age: 90\n    format: text\n",
+			true,
+		},
+		{
+			"empty parent",
+			"logging: # settings\npvr: {}\n",
+			"logging: # settings\n  format: text\n  file:\n    max_age: 90\npvr: {}\n",
+			true,
+		},
+		{"flow mapping", "logging: {console: stdout}\n", "", false},
+	}
+
+	for _, tc := range tests {
+		updated, ok := insertConfigDefaults([]byte(tc.config), missing)
+		if ok != tc.ok {
+			t.Fatalf("Expected ok %v for %s but got: %v", tc.ok, tc.name, ok)
+		}
+		if string(updated) != tc.expected {
+			t.Errorf("Expected config for %s:\n%s\nbut got:\n%s", tc.name, tc.expected, updated)
+		}
+	}
+}
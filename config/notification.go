@@ -0,0 +1,7 @@
+package config
+
+type Notification struct {
+	Type     string
+	URL      string
+	Statuses []string
+}
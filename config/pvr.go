@@ -1,15 +1,187 @@
 package config
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	urlApiPathRegex = regexp.MustCompile(`(?i)/api(/v\d+)?/*$`)
+)
 
 type Pvr struct {
-	Type         string
-	URL          string
-	ApiKey       string       `mapstructure:"api_key"`
-	RetryDaysAge RetryDaysAge `mapstructure:"retry_days_age"`
+	Type          string
+	URL           string
+	URLBase       string       `mapstructure:"url_base"`
+	APIPath       string       `mapstructure:"api_path"`
+	ApiKey        string       `mapstructure:"api_key"`
+	ApiKeyFile    string       `mapstructure:"api_key_file"`
+	RetryDaysAge  RetryDaysAge `mapstructure:"retry_days_age"`
+	PageSize      int          `mapstructure:"page_size"`
+	HTTP          HTTP         `mapstructure:"http"`
+	TLS           TLS
+	Proxy         string
+	Headers       map[string]string
+	BasicAuth     BasicAuth `mapstructure:"basic_auth"`
+	Search        Search
+	SearchCommand SearchCommand `mapstructure:"search_command"`
+	Throttle      Throttle
 }
 
 type RetryDaysAge struct {
-	Missing time.Duration
-	Cutoff  time.Duration
+	Missing RetryAge
+	Cutoff  RetryAge
+}
+
+type HTTP struct {
+	Timeout time.Duration
+	// Retries is nil when not set, 0 disables retries
+	Retries *int
+}
+
+type TLS struct {
+	CAFile             string `mapstructure:"ca_file"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+	CertFile           string `mapstructure:"cert_file"`
+	KeyFile            string `mapstructure:"key_file"`
+}
+
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+type Search struct {
+	QueueSize  int           `mapstructure:"queue_size"`
+	MaxSearch  int           `mapstructure:"max_search"`
+	SearchSize int           `mapstructure:"search_size"`
+	BatchDelay time.Duration `mapstructure:"batch_delay"`
+}
+
+type SearchCommand struct {
+	MaxDuration  time.Duration `mapstructure:"max_duration"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	OnTimeout    string        `mapstructure:"on_timeout"`
+}
+
+type Throttle struct {
+	MinFreeSpaceGb  int64 `mapstructure:"min_free_space_gb"`
+	DownloadClients bool  `mapstructure:"download_clients"`
+}
+
+/* Public */
+
+// Validate checks the pvr settings that can be checked without connecting to the pvr
+func (p Pvr) Validate() []error {
+	var errs []error
+
+	// validate url
+	if p.URL == "" {
+		errs = append(errs, errors.New("url is not set"))
+	} else if u, err := url.Parse(p.URL); err != nil {
+		errs = append(errs, fmt.Errorf("url is invalid: %v", err))
+	} else if u.Scheme == "unix" {
+		if u.Host+u.Path == "" {
+			errs = append(errs, fmt.Errorf("url must include the unix socket path: %q", p.URL))
+		}
+		if p.Proxy != "" {
+			errs = append(errs, errors.New("proxy can not be used with a unix socket url"))
+		}
+	} else if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("url must be an absolute http(s) or unix socket url: %q", p.URL))
+	}
+
+	// validate proxy
+	if p.Proxy != "" {
+		u, err := url.Parse(p.Proxy)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			errs = append(errs, fmt.Errorf("proxy must be an absolute http(s) or socks5 url: %q", p.Proxy))
+		}
+	}
+
+	// validate api path
+	if p.APIPath != "" && !strings.HasPrefix(p.APIPath, "/") {
+		errs = append(errs, fmt.Errorf("api_path must start with a /: %q", p.APIPath))
+	}
+
+	// validate tls
+	if (p.TLS.CertFile == "") != (p.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls.cert_file and tls.key_file must both be set"))
+	}
+
+	// validate api key
+	if p.ApiKey == "" {
+		errs = append(errs, errors.New("api_key is not set"))
+	}
+
+	// validate retry ages
+	if p.RetryDaysAge.Missing <= 0 {
+		errs = append(errs, errors.New("retry_days_age.missing must be greater than zero"))
+	}
+	if p.RetryDaysAge.Cutoff <= 0 {
+		errs = append(errs, errors.New("retry_days_age.cutoff must be greater than zero"))
+	}
+
+	// validate search settings
+	if p.PageSize < 0 {
+		errs = append(errs, errors.New("page_size must not be negative"))
+	}
+	if p.HTTP.Timeout < 0 {
+		errs = append(errs, errors.New("http.timeout must not be negative"))
+	}
+	if p.HTTP.Retries != nil && *p.HTTP.Retries < 0 {
+		errs = append(errs, errors.New("http.retries must not be negative"))
+	}
+	if p.Search.SearchSize < 0 {
+		errs = append(errs, errors.New("search.search_size must not be negative"))
+	}
+	if p.Search.BatchDelay < 0 {
+		errs = append(errs, errors.New("search.batch_delay must not be negative"))
+	}
+	if p.SearchCommand.PollInterval < 0 {
+		errs = append(errs, errors.New("search_command.poll_interval must not be negative"))
+	}
+
+	// validate search command
+	switch strings.ToLower(p.SearchCommand.OnTimeout) {
+	case "", "continue", "abort":
+		break
+	default:
+		errs = append(errs, fmt.Errorf("search_command.on_timeout must be continue or abort: %q",
+			p.SearchCommand.OnTimeout))
+	}
+
+	return errs
+}
+
+func (t Throttle) Enabled() bool {
+	return t.MinFreeSpaceGb > 0 || t.DownloadClients
+}
+
+/* Private */
+
+// splitApiPath moves an api path included in the url, e.g. https://sonarr.domain.com/api/v3, to api_path
+func (p *Pvr) splitApiPath() bool {
+	if p.APIPath != "" || strings.HasPrefix(strings.ToLower(p.URL), "unix:") {
+		return false
+	}
+
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return false
+	}
+
+	loc := urlApiPathRegex.FindStringIndex(u.Path)
+	if loc == nil {
+		return false
+	}
+
+	p.APIPath = strings.TrimRight(u.Path[loc[0]:], "/")
+	u.Path = u.Path[:loc[0]]
+	p.URL = u.String()
+	return true
 }
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+)
+
+/* Test Api Path */
+
+func TestSplitApiPath(t *testing.T) {
+	tests := []struct {
+		url             string
+		expectedUrl     string
+		expectedApiPath string
+	}{
+		{"https://sonarr.domain.com", "https://sonarr.domain.com", ""},
+		{"https://sonarr.domain.com/api/v3", "https://sonarr.domain.com", "/api/v3"},
+		{"https://domain.com/sonarr/api/", "https://domain.com/sonarr", "/api"},
+		{"https://domain.com/apis", "https://domain.com/apis", ""},
+		{"https://domain.com/api/sonarr", "https://domain.com/api/sonarr", ""},
+		{"unix:///var/run/api", "unix:///var/run/api", ""},
+	}
+
+	for _, tc := range tests {
+		p := &Pvr{URL: tc.url}
+		p.splitApiPath()
+
+		if p.URL != tc.expectedUrl || p.APIPath != tc.expectedApiPath {
+			t.Errorf("Expected url %q and api path %q for %q but got: %q and %q", tc.expectedUrl,
+				tc.expectedApiPath, tc.url, p.URL, p.APIPath)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	envPrefix         = "WANTARR"
+	envVarRegex       = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	envNameCleanRegex = regexp.MustCompile(`[^A-Z0-9]+`)
+)
+
+/* Public */
+
+// PvrEnvName returns the environment variable overriding a pvr setting, e.g. WANTARR_PVR_SONARR_API_KEY
+func PvrEnvName(pvrName string, setting string) string {
+	name := envNameCleanRegex.ReplaceAllString(strings.ToUpper(pvrName), "_")
+	return fmt.Sprintf("%s_PVR_%s_%s", envPrefix, name, strings.ToUpper(setting))
+}
+
+// DatabaseEnvName returns the environment variable overriding a database setting, e.g. WANTARR_DATABASE_DSN
+func DatabaseEnvName(setting string) string {
+	return fmt.Sprintf("%s_DATABASE_%s", envPrefix, strings.ToUpper(setting))
+}
+
+/* Private */
+
+func (d *Database) resolveSecrets() error {
+	// apply environment override
+	if env, ok := os.LookupEnv(DatabaseEnvName("dsn")); ok {
+		d.DSN = env
+	}
+
+	// interpolate environment variables
+	var err error
+	if d.DSN, err = expandEnv(d.DSN); err != nil {
+		return errors.WithMessage(err, "failed interpolating dsn")
+	}
+
+	return nil
+}
+
+func (p *Pvr) resolveSecrets(pvrName string) error {
+	// apply environment overrides (an api key override replaces an api_key_file from the config, and vice versa)
+	if env, ok := os.LookupEnv(PvrEnvName(pvrName, "url")); ok {
+		p.URL = env
+	}
+
+	envApiKey, apiKeyOverridden := os.LookupEnv(PvrEnvName(pvrName, "api_key"))
+	envApiKeyFile, apiKeyFileOverridden := os.LookupEnv(PvrEnvName(pvrName, "api_key_file"))
+
+	switch {
+	case apiKeyOverridden && apiKeyFileOverridden:
+		return fmt.Errorf("%s and %s must not both be set", PvrEnvName(pvrName, "api_key"),
+			PvrEnvName(pvrName, "api_key_file"))
+	case apiKeyOverridden:
+		p.ApiKey, p.ApiKeyFile = envApiKey, ""
+	case apiKeyFileOverridden:
+		p.ApiKey, p.ApiKeyFile = "", envApiKeyFile
+	}
+
+	// interpolate environment variables
+	var err error
+	if p.URL, err = expandEnv(p.URL); err != nil {
+		return errors.WithMessage(err, "failed interpolating url")
+	}
+	if p.ApiKey, err = expandEnv(p.ApiKey); err != nil {
+		return errors.WithMessage(err, "failed interpolating api_key")
+	}
+	if p.ApiKeyFile, err = expandEnv(p.ApiKeyFile); err != nil {
+		return errors.WithMessage(err, "failed interpolating api_key_file")
+	}
+	if p.Proxy, err = expandEnv(p.Proxy); err != nil {
+		return errors.WithMessage(err, "failed interpolating proxy")
+	}
+	if p.BasicAuth.Username, err = expandEnv(p.BasicAuth.Username); err != nil {
+		return errors.WithMessage(err, "failed interpolating basic_auth.username")
+	}
+	if p.BasicAuth.Password, err = expandEnv(p.BasicAuth.Password); err != nil {
+		return errors.WithMessage(err, "failed interpolating basic_auth.password")
+	}
+	for header, value := range p.Headers {
+		if p.Headers[header], err = expandEnv(value); err != nil {
+			return errors.WithMessagef(err, "failed interpolating header: %s", header)
+		}
+	}
+
+	// read api key from file
+	if p.ApiKeyFile == "" {
+		return nil
+	}
+
+	if p.ApiKey != "" {
+		return errors.New("api_key and api_key_file must not both be set")
+	}
+
+	b, err := ioutil.ReadFile(p.ApiKeyFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed reading api_key_file: %q", p.ApiKeyFile)
+	}
+
+	p.ApiKey = strings.TrimSpace(string(b))
+	return nil
+}
+
+func expandEnv(value string) (string, error) {
+	var missing []string
+
+	expanded := envVarRegex.ReplaceAllStringFunc(value, func(s string) string {
+		name := envVarRegex.FindStringSubmatch(s)[1]
+
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+
+		return env
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variables are not set: %s", strings.Join(missing, ", "))
+	}
+
+	return expanded, nil
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/* Test Secrets */
+
+func TestExpandEnv(t *testing.T) {
+	os.Setenv("WANTARR_TEST_HOST", "sonarr.domain.com")
+	os.Setenv("WANTARR_TEST_EMPTY", "")
+	os.Unsetenv("WANTARR_TEST_UNSET")
+	defer os.Unsetenv("WANTARR_TEST_HOST")
+	defer os.Unsetenv("WANTARR_TEST_EMPTY")
+
+	tests := []struct {
+		value       string
+		expected    string
+		expectedErr bool
+	}{
+		{"https://${WANTARR_TEST_HOST}/sonarr", "https://sonarr.domain.com/sonarr", false},
+		{"${WANTARR_TEST_EMPTY}", "", false},
+		{"$WANTARR_TEST_HOST", "$WANTARR_TEST_HOST", false},
+		{"no variables", "no variables", false},
+		{"${WANTARR_TEST_UNSET}", "", true},
+		{"${WANTARR_TEST_HOST}/${WANTARR_TEST_UNSET}", "", true},
+	}
+
+	for _, tc := range tests {
+		expanded, err := expandEnv(tc.value)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("Expected error %v for %q but got: %v", tc.expectedErr, tc.value, err)
+			continue
+		}
+
+		if expanded != tc.expected {
+			t.Errorf("Expected %q for %q but got: %q", tc.expected, tc.value, expanded)
+		}
+	}
+}
+
+func TestResolvePvrSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wantarr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyFile := filepath.Join(dir, "api_key")
+	if err := ioutil.WriteFile(keyFile, []byte("  file-key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("WANTARR_TEST_KEY_FILE", keyFile)
+	defer os.Unsetenv("WANTARR_TEST_KEY_FILE")
+
+	tests := []struct {
+		name           string
+		pvr            Pvr
+		env            map[string]string
+		expectedApiKey string
+		expectedErr    bool
+	}{
+		{"api key", Pvr{ApiKey: "key"}, nil, "key", false},
+		{"api key file trimmed", Pvr{ApiKeyFile: keyFile}, nil, "file-key", false},
+		{"api key file from env", Pvr{ApiKeyFile: "${WANTARR_TEST_KEY_FILE}"}, nil, "file-key", false},
+		{"api key file missing", Pvr{ApiKeyFile: filepath.Join(dir, "missing")}, nil, "", true},
+		{"api key and api key file", Pvr{ApiKey: "key", ApiKeyFile: keyFile}, nil, "", true},
+		{"api key override", Pvr{ApiKey: "key"}, map[string]string{"WANTARR_PVR_SONARR_4K_API_KEY": "env-key"},
+			"env-key", false},
+		{"api key file override", Pvr{ApiKey: ""},
+			map[string]string{"WANTARR_PVR_SONARR_4K_API_KEY_FILE": keyFile}, "file-key", false},
+		{"api key override of api key file", Pvr{ApiKeyFile: filepath.Join(dir, "missing")},
+			map[string]string{"WANTARR_PVR_SONARR_4K_API_KEY": "env-key"}, "env-key", false},
+		{"api key file override of api key", Pvr{ApiKey: "key"},
+			map[string]string{"WANTARR_PVR_SONARR_4K_API_KEY_FILE": keyFile}, "file-key", false},
+		{"api key and api key file overrides", Pvr{}, map[string]string{
+			"WANTARR_PVR_SONARR_4K_API_KEY":      "env-key",
+			"WANTARR_PVR_SONARR_4K_API_KEY_FILE": keyFile,
+		}, "", true},
+	}
+
+	for _, tc := range tests {
+		for name, value := range tc.env {
+			os.Setenv(name, value)
+		}
+
+		pvr := tc.pvr
+		err := pvr.resolveSecrets("sonarr-4k")
+
+		for name := range tc.env {
+			os.Unsetenv(name)
+		}
+
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("Expected error %v for %s but got: %v", tc.expectedErr, tc.name, err)
+			continue
+		}
+
+		if err == nil && pvr.ApiKey != tc.expectedApiKey {
+			t.Errorf("Expected api key %q for %s but got: %q", tc.expectedApiKey, tc.name, pvr.ApiKey)
+		}
+	}
+}
+
+func TestPvrEnvName(t *testing.T) {
+	tests := []struct {
+		pvrName  string
+		setting  string
+		expected string
+	}{
+		{"sonarr", "api_key", "WANTARR_PVR_SONARR_API_KEY"},
+		{"sonarr-4k", "url", "WANTARR_PVR_SONARR_4K_URL"},
+		{"radarr.uhd", "api_key_file", "WANTARR_PVR_RADARR_UHD_API_KEY_FILE"},
+	}
+
+	for _, tc := range tests {
+		if name := PvrEnvName(tc.pvrName, tc.setting); name != tc.expected {
+			t.Errorf("Expected %q for %s %s but got: %q", tc.expected, tc.pvrName, tc.setting, name)
+		}
+	}
+}
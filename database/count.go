@@ -1,7 +1,7 @@
 package database
 
-func GetItemsCount(pvrName string, wantedType string) int {
+func (s *sqlStore) GetItemsCount(pvrName string, wantedType string) int {
 	itemCount := 0
-	db.Model(&MediaItem{}).Where("pvr_name = ? AND wanted_type = ?", pvrName, wantedType).Count(&itemCount)
+	s.db.Model(&MediaItem{}).Where("pvr_name = ? AND wanted_type = ?", pvrName, wantedType).Count(&itemCount)
 	return itemCount
 }
@@ -1,36 +1,105 @@
 package database
 
 import (
+	"regexp"
+	"strings"
+
 	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
 	"github.com/l3uddz/wantarr/logger"
-	stringutils "github.com/l3uddz/wantarr/utils/strings"
+	"github.com/pkg/errors"
 )
 
 var (
-	db  *gorm.DB
 	log = logger.GetLogger("db")
+
+	dsnPasswordRegex      = regexp.MustCompile(`^([a-z0-9]+://)?([^:@/]*):[^/]*@`)
+	dsnPasswordParamRegex = regexp.MustCompile(`(?i)(^|[?&\s])((?:ssl)?password|passwd|pwd)=[^&\s]*`)
 )
 
-func Init(databaseFilePath string) error {
-	// show log
-	log.Infof("Using %s = %q", stringutils.StringLeftJust("DATABASE", " ", 10), databaseFilePath)
+/* Structs */
 
-	// open database
-	if dtb, err := gorm.Open("sqlite3", databaseFilePath); err != nil {
-		return err
-	} else {
-		db = dtb
+type sqlStore struct {
+	db *gorm.DB
+}
+
+/* Initializer */
+
+// NewSQLiteStore opens the sqlite database file
+func NewSQLiteStore(databaseFilePath string) (Store, error) {
+	return newSQLStore("sqlite3", databaseFilePath)
+}
+
+// NewSQLStore opens the database of a postgres://, postgresql://, mysql:// or sqlite:// dsn
+func NewSQLStore(dsn string) (Store, error) {
+	dialect, source, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
 	}
 
-	// migrate schema
-	db.AutoMigrate(&MediaItem{})
+	return newSQLStore(dialect, source)
+}
+
+/* Public */
+
+// ParseDSN returns the gorm dialect and driver data source of a dsn
+func ParseDSN(dsn string) (string, string, error) {
+	pos := strings.Index(dsn, "://")
+	if pos < 0 {
+		return "", "", errors.Errorf("dsn must start with postgres://, mysql:// or sqlite://: %q", RedactDSN(dsn))
+	}
 
-	return nil
+	switch scheme, source := strings.ToLower(dsn[:pos]), dsn[pos+3:]; scheme {
+	case "postgres", "postgresql":
+		// lib/pq accepts the url as is
+		return "postgres", dsn, nil
+	case "mysql":
+		// times are scanned into time.Time
+		if !strings.Contains(source, "parseTime=") {
+			if strings.Contains(source, "?") {
+				source += "&parseTime=true"
+			} else {
+				source += "?parseTime=true"
+			}
+		}
+		return "mysql", source, nil
+	case "sqlite", "sqlite3":
+		if source == "" {
+			return "", "", errors.New("sqlite dsn must include the database file path")
+		}
+		return "sqlite3", source, nil
+	default:
+		return "", "", errors.Errorf("unsupported dsn scheme: %q (supported: postgres, mysql, sqlite)", scheme)
+	}
+}
+
+// RedactDSN returns the dsn with any password, in the user info or parameters, replaced so it can be logged
+func RedactDSN(dsn string) string {
+	dsn = dsnPasswordRegex.ReplaceAllString(dsn, "${1}${2}:*****@")
+	return dsnPasswordParamRegex.ReplaceAllString(dsn, "${1}${2}=*****")
 }
 
-func Close() {
-	if err := db.Close(); err != nil {
-		log.WithError(err).Error("Failed closing database gracefully...")
+/* Private */
+
+func newSQLStore(dialect string, source string) (Store, error) {
+	// open database
+	db, err := gorm.Open(dialect, source)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed opening %s database", dialect)
 	}
+
+	// serialize access to the database file (runs may be searching concurrently)
+	if dialect == "sqlite3" {
+		db.DB().SetMaxOpenConns(1)
+	}
+
+	return &sqlStore{db: db}, nil
+}
+
+/* Interface Implements */
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
 }
@@ -5,7 +5,7 @@ import (
 	"github.com/pkg/errors"
 )
 
-func DeleteMissingItems(pvrName string, wantedType string, newMediaItems []pvr.MediaItem) (int, error) {
+func (s *sqlStore) DeleteMissingItems(pvrName string, wantedType string, newMediaItems []pvr.MediaItem) (int, error) {
 	// build slice of new item ids
 	newItemIds := make(map[int]*string)
 
@@ -14,7 +14,7 @@ func DeleteMissingItems(pvrName string, wantedType string, newMediaItems []pvr.M
 	}
 
 	// begin transaction
-	tx := db.Begin()
+	tx := s.db.Begin()
 
 	// retrieve existing items
 	var dbItems []MediaItem
@@ -48,3 +48,26 @@ func DeleteMissingItems(pvrName string, wantedType string, newMediaItems []pvr.M
 
 	return removedItems, nil
 }
+
+func (s *sqlStore) DeleteMediaItems(pvrName string, wantedType string, itemIds []int) (int, error) {
+	if len(itemIds) == 0 {
+		return 0, nil
+	}
+
+	query := s.filterQuery(pvrName, wantedType).Where("id IN (?)", itemIds)
+	result := query.Unscoped().Delete(&MediaItem{})
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, "failed removing media items")
+	}
+
+	return int(result.RowsAffected), nil
+}
+
+func (s *sqlStore) DeleteSeriesMediaItems(pvrName string, seriesId int) (int, error) {
+	result := s.filterQuery(pvrName, "").Where("series_id = ?", seriesId).Unscoped().Delete(&MediaItem{})
+	if result.Error != nil {
+		return 0, errors.Wrapf(result.Error, "failed removing media items for series: %d", seriesId)
+	}
+
+	return int(result.RowsAffected), nil
+}
@@ -5,7 +5,7 @@ import (
 	"time"
 )
 
-func GetMediaItems(pvrName string, wantedType string, excludeFuture bool) ([]MediaItem, error) {
+func (s *sqlStore) GetMediaItems(pvrName string, wantedType string, excludeFuture bool) ([]MediaItem, error) {
 	var mediaItems []MediaItem
 
 	// generate query
@@ -21,7 +21,7 @@ func GetMediaItems(pvrName string, wantedType string, excludeFuture bool) ([]Med
 	}
 
 	// exec query
-	if err := db.Where(sqlQuery, sqlParams...).Order("air_date_utc desc").Find(&mediaItems).Error; err != nil {
+	if err := s.db.Where(sqlQuery, sqlParams...).Order("air_date_utc desc").Find(&mediaItems).Error; err != nil {
 		return nil, errors.Wrap(err, "failed querying for media items")
 	}
 
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"github.com/l3uddz/wantarr/pvr"
+	"github.com/pkg/errors"
+)
+
+func (s *sqlStore) AddSearchHistory(runId uint, pvrName string, wantedType string, itemsCount int,
+	command *pvr.SearchCommand) error {
+	history := SearchHistory{
+		RunId:      runId,
+		PvrName:    pvrName,
+		WantedType: wantedType,
+		CommandId:  command.Id,
+		Status:     command.Status,
+		Message:    command.Message,
+		ItemsCount: itemsCount,
+		StartedUtc: command.Started,
+		EndedUtc:   command.Ended,
+	}
+
+	if err := s.db.Create(&history).Error; err != nil {
+		return errors.Wrapf(err, "failed inserting search history for command: %d", command.Id)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) GetSearchHistory(pvrName string, wantedType string, limit int) ([]SearchHistory, error) {
+	var history []SearchHistory
+
+	if err := s.filterQuery(pvrName, wantedType).Order("id desc").Limit(limit).Find(&history).Error; err != nil {
+		return nil, errors.Wrap(err, "failed querying for search history")
+	}
+
+	return history, nil
+}
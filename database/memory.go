@@ -0,0 +1,286 @@
+package database
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/l3uddz/wantarr/pvr"
+	"github.com/pkg/errors"
+)
+
+/* Structs */
+
+// MemoryStore keeps state in memory only, it is lost when the process exits
+type MemoryStore struct {
+	items   map[mediaItemKey]MediaItem
+	history []SearchHistory
+	runs    []SearchRun
+	mtx     sync.Mutex
+}
+
+type mediaItemKey struct {
+	id         int
+	pvrName    string
+	wantedType string
+}
+
+/* Initializer */
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		items: make(map[mediaItemKey]MediaItem),
+	}
+}
+
+/* Interface Implements */
+
+func (s *MemoryStore) GetItemsCount(pvrName string, wantedType string) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	itemCount := 0
+	for key := range s.items {
+		if key.pvrName == pvrName && key.wantedType == wantedType {
+			itemCount++
+		}
+	}
+
+	return itemCount
+}
+
+func (s *MemoryStore) GetMediaItems(pvrName string, wantedType string, excludeFuture bool) ([]MediaItem, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var mediaItems []MediaItem
+	now := time.Now().UTC()
+
+	for key, item := range s.items {
+		if key.pvrName != pvrName || key.wantedType != wantedType {
+			continue
+		}
+
+		if excludeFuture && item.AirDateUtc.After(now) {
+			continue
+		}
+
+		mediaItems = append(mediaItems, copyMediaItem(item))
+	}
+
+	sort.Slice(mediaItems, func(i, j int) bool {
+		if !mediaItems[i].AirDateUtc.Equal(mediaItems[j].AirDateUtc) {
+			return mediaItems[i].AirDateUtc.After(mediaItems[j].AirDateUtc)
+		}
+		return mediaItems[i].Id < mediaItems[j].Id
+	})
+
+	return mediaItems, nil
+}
+
+func (s *MemoryStore) SetMediaItems(pvrName string, wantedType string, mediaItems []pvr.MediaItem) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for _, item := range mediaItems {
+		key := mediaItemKey{id: item.ItemId, pvrName: pvrName, wantedType: wantedType}
+
+		// create item if not exists
+		mediaItem, ok := s.items[key]
+		if !ok {
+			mediaItem = MediaItem{
+				Id:         item.ItemId,
+				PvrName:    pvrName,
+				WantedType: wantedType,
+			}
+		}
+
+		mediaItem.SeriesId = item.SeriesId
+		mediaItem.AirDateUtc = item.AirDateUtc
+
+		// update item
+		if !item.LastSearch.IsZero() {
+			lastSearch := item.LastSearch
+			mediaItem.LastSearchDateUtc = &lastSearch
+		}
+
+		s.items[key] = mediaItem
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteMissingItems(pvrName string, wantedType string,
+	newMediaItems []pvr.MediaItem) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	// build slice of new item ids
+	newItemIds := make(map[int]*string)
+
+	for _, item := range newMediaItems {
+		newItemIds[item.ItemId] = nil
+	}
+
+	// remove items that no longer exist
+	removedItems := 0
+
+	for key := range s.items {
+		if key.pvrName != pvrName || key.wantedType != wantedType {
+			continue
+		}
+
+		if _, ok := newItemIds[key.id]; !ok {
+			delete(s.items, key)
+			removedItems++
+		}
+	}
+
+	return removedItems, nil
+}
+
+func (s *MemoryStore) DeleteMediaItems(pvrName string, wantedType string, itemIds []int) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	ids := make(map[int]*string)
+	for _, id := range itemIds {
+		ids[id] = nil
+	}
+
+	removedItems := 0
+
+	for key := range s.items {
+		if _, ok := ids[key.id]; ok && matchesFilter(key.pvrName, key.wantedType, pvrName, wantedType) {
+			delete(s.items, key)
+			removedItems++
+		}
+	}
+
+	return removedItems, nil
+}
+
+func (s *MemoryStore) DeleteSeriesMediaItems(pvrName string, seriesId int) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	removedItems := 0
+
+	for key, item := range s.items {
+		if item.SeriesId == seriesId && matchesFilter(key.pvrName, key.wantedType, pvrName, "") {
+			delete(s.items, key)
+			removedItems++
+		}
+	}
+
+	return removedItems, nil
+}
+
+func (s *MemoryStore) AddSearchHistory(runId uint, pvrName string, wantedType string, itemsCount int,
+	command *pvr.SearchCommand) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.history = append(s.history, SearchHistory{
+		Id:         uint(len(s.history) + 1),
+		RunId:      runId,
+		PvrName:    pvrName,
+		WantedType: wantedType,
+		CommandId:  command.Id,
+		Status:     command.Status,
+		Message:    command.Message,
+		ItemsCount: itemsCount,
+		StartedUtc: command.Started,
+		EndedUtc:   command.Ended,
+	})
+
+	return nil
+}
+
+func (s *MemoryStore) GetSearchHistory(pvrName string, wantedType string, limit int) ([]SearchHistory, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var history []SearchHistory
+
+	for pos := len(s.history) - 1; pos >= 0 && (limit <= 0 || len(history) < limit); pos-- {
+		if matchesFilter(s.history[pos].PvrName, s.history[pos].WantedType, pvrName, wantedType) {
+			history = append(history, s.history[pos])
+		}
+	}
+
+	return history, nil
+}
+
+func (s *MemoryStore) AddSearchRun(run *SearchRun) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	run.Id = uint(len(s.runs) + 1)
+	s.runs = append(s.runs, copySearchRun(*run))
+
+	return nil
+}
+
+func (s *MemoryStore) UpdateSearchRun(run *SearchRun) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if run.Id == 0 || int(run.Id) > len(s.runs) {
+		return errors.Errorf("failed updating search run: %d", run.Id)
+	}
+
+	s.runs[run.Id-1] = copySearchRun(*run)
+
+	return nil
+}
+
+func (s *MemoryStore) GetSearchRuns(pvrName string, wantedType string, status string,
+	limit int) ([]SearchRun, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var runs []SearchRun
+
+	for pos := len(s.runs) - 1; pos >= 0 && (limit <= 0 || len(runs) < limit); pos-- {
+		run := s.runs[pos]
+		if !matchesFilter(run.PvrName, run.WantedType, pvrName, wantedType) {
+			continue
+		}
+		if status != "" && run.Status != status {
+			continue
+		}
+
+		runs = append(runs, copySearchRun(run))
+	}
+
+	return runs, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+/* Private */
+
+func matchesFilter(itemPvrName string, itemWantedType string, pvrName string, wantedType string) bool {
+	return (pvrName == "" || itemPvrName == pvrName) && (wantedType == "" || itemWantedType == wantedType)
+}
+
+func copyMediaItem(item MediaItem) MediaItem {
+	if item.LastSearchDateUtc != nil {
+		lastSearch := *item.LastSearchDateUtc
+		item.LastSearchDateUtc = &lastSearch
+	}
+
+	return item
+}
+
+func copySearchRun(run SearchRun) SearchRun {
+	if run.EndedUtc != nil {
+		ended := *run.EndedUtc
+		run.EndedUtc = &ended
+	}
+
+	return run
+}
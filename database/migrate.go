@@ -0,0 +1,229 @@
+package database
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+/* Structs */
+
+type SchemaVersion struct {
+	Version    int `gorm:"primary_key;auto_increment:false"`
+	Name       string
+	AppliedUtc time.Time
+}
+
+// Migration is a schema migration, AppliedUtc is nil until it has been applied
+type Migration struct {
+	Version    int        `json:"version"`
+	Name       string     `json:"name"`
+	AppliedUtc *time.Time `json:"applied,omitempty"`
+}
+
+type migration struct {
+	version int
+	name    string
+	migrate func(tx *gorm.DB) error
+}
+
+/* Interfaces */
+
+// Migrator is implemented by stores with a versioned schema
+type Migrator interface {
+	// Migrations returns all known migrations and when they were applied
+	Migrations() ([]Migration, error)
+	// Migrate applies pending migrations in order, returning those applied
+	Migrate() ([]Migration, error)
+}
+
+/* Vars */
+
+// migrations are applied in order, each in a transaction (mysql commits schema changes immediately).
+// Migrations must never be changed once released, add a new migration instead. Migrations must not use the
+// structs in schema.go, which describe the latest schema, but a snapshot of the schema they migrate to.
+var migrations = []migration{
+	{1, "initial schema", func(tx *gorm.DB) error {
+		// databases created before versioning already have these tables, which AutoMigrate leaves as is
+		return tx.AutoMigrate(&mediaItemV1{}, &searchHistoryV1{}, &searchRunV1{}).Error
+	}},
+	{2, "search run owner", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&searchRunV2{}).Error
+	}},
+}
+
+/* Schema Snapshots */
+
+// schema version 1
+type mediaItemV1 struct {
+	Id                int    `gorm:"primary_key;auto_increment:false"`
+	PvrName           string `gorm:"primary_key"`
+	WantedType        string `gorm:"primary_key"`
+	SeriesId          int
+	AirDateUtc        time.Time
+	LastSearchDateUtc *time.Time `gorm:"null"`
+}
+
+type searchHistoryV1 struct {
+	Id         uint `gorm:"primary_key"`
+	RunId      uint
+	PvrName    string
+	WantedType string
+	CommandId  int
+	Status     string
+	Message    string `gorm:"type:text"`
+	ItemsCount int
+	StartedUtc time.Time
+	EndedUtc   time.Time
+}
+
+type searchRunV1 struct {
+	Id            uint `gorm:"primary_key"`
+	PvrName       string
+	WantedType    string
+	Status        string
+	Reason        string `gorm:"type:text"`
+	SearchedItems int
+	Batches       int
+	FailedBatches int
+	StartedUtc    time.Time
+	EndedUtc      *time.Time `gorm:"null"`
+}
+
+// schema version 2
+type searchRunV2 struct {
+	Id            uint `gorm:"primary_key"`
+	PvrName       string
+	WantedType    string
+	Status        string
+	Reason        string `gorm:"type:text"`
+	SearchedItems int
+	Batches       int
+	FailedBatches int
+	StartedUtc    time.Time
+	EndedUtc      *time.Time `gorm:"null"`
+	Host          string
+	Pid           int
+}
+
+func (mediaItemV1) TableName() string     { return "media_items" }
+func (searchHistoryV1) TableName() string { return "search_histories" }
+func (searchRunV1) TableName() string     { return "search_runs" }
+func (searchRunV2) TableName() string     { return "search_runs" }
+
+/* Interface Implements */
+
+func (s *sqlStore) Migrations() ([]Migration, error) {
+	applied, err := s.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Migration, 0, len(migrations))
+	for _, m := range migrations {
+		status := Migration{Version: m.version, Name: m.name}
+		if v, ok := applied[m.version]; ok {
+			appliedUtc := v.AppliedUtc
+			status.AppliedUtc = &appliedUtc
+		}
+
+		result = append(result, status)
+	}
+
+	return result, nil
+}
+
+func (s *sqlStore) Migrate() ([]Migration, error) {
+	applied, err := s.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Migration
+
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+
+		l := log.WithField("schema_version", m.version)
+		l.Infof("Applying database migration: %s", m.name)
+
+		v, err := s.applyMigration(m)
+		if err != nil {
+			// another instance sharing the database may have applied it concurrently
+			if applied, aerr := s.appliedVersions(); aerr == nil {
+				if _, ok := applied[m.version]; ok {
+					l.Info("Database migration was applied by another instance")
+					continue
+				}
+			}
+
+			return result, err
+		}
+
+		result = append(result, Migration{Version: v.Version, Name: v.Name, AppliedUtc: &v.AppliedUtc})
+	}
+
+	return result, nil
+}
+
+/* Private */
+
+func (s *sqlStore) appliedVersions() (map[int]SchemaVersion, error) {
+	// create version table
+	if err := s.db.AutoMigrate(&SchemaVersion{}).Error; err != nil {
+		return nil, errors.WithMessage(err, "failed creating schema version table")
+	}
+
+	var versions []SchemaVersion
+	if err := s.db.Order("version").Find(&versions).Error; err != nil {
+		return nil, errors.Wrap(err, "failed querying for schema versions")
+	}
+
+	applied := make(map[int]SchemaVersion)
+	for _, v := range versions {
+		applied[v.Version] = v
+	}
+
+	// refuse to use a database migrated by a newer version
+	if len(versions) > 0 {
+		latest := migrations[len(migrations)-1].version
+		if current := versions[len(versions)-1].Version; current > latest {
+			return nil, errors.Errorf("database schema version %d is newer than the latest supported version %d, "+
+				"upgrade wantarr", current, latest)
+		}
+	}
+
+	return applied, nil
+}
+
+func (s *sqlStore) applyMigration(m migration) (*SchemaVersion, error) {
+	// begin transaction
+	tx := s.db.Begin()
+
+	if err := m.migrate(tx); err != nil {
+		tx.Rollback()
+		return nil, errors.WithMessagef(err, "failed applying database migration %d: %s", m.version, m.name)
+	}
+
+	// record version
+	v := &SchemaVersion{
+		Version:    m.version,
+		Name:       m.name,
+		AppliedUtc: time.Now().UTC(),
+	}
+
+	if err := tx.Create(v).Error; err != nil {
+		tx.Rollback()
+		return nil, errors.Wrapf(err, "failed recording schema version: %d", m.version)
+	}
+
+	// commit transaction
+	if err := tx.Commit().Error; err != nil {
+		return nil, errors.Wrapf(err, "failed committing database migration: %d", m.version)
+	}
+
+	return v, nil
+}
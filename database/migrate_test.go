@@ -0,0 +1,107 @@
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+/* Test Migrations */
+
+func TestMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wantarr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	databaseFilePath := filepath.Join(dir, "vault.db")
+
+	// create a database from before versioning
+	db, err := gorm.Open("sqlite3", databaseFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&mediaItemV1{}, &searchHistoryV1{}, &searchRunV1{})
+	db.Create(&mediaItemV1{Id: 1, PvrName: "sonarr", WantedType: "missing", AirDateUtc: time.Now().UTC()})
+	db.Close()
+
+	store, err := NewSQLiteStore(databaseFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	m := store.(Migrator)
+
+	// all migrations are pending
+	status, err := m.Migrations()
+	if err != nil {
+		t.Fatalf("Expected no error getting migrations but got: %v", err)
+	}
+	if len(status) != len(migrations) || status[0].AppliedUtc != nil {
+		t.Errorf("Expected %d pending migrations but got: %+v", len(migrations), status)
+	}
+
+	// migrations are applied once
+	applied, err := m.Migrate()
+	if err != nil || len(applied) != len(migrations) {
+		t.Fatalf("Expected %d applied migrations but got: %+v (err: %v)", len(migrations), applied, err)
+	}
+	if applied, err := m.Migrate(); err != nil || len(applied) != 0 {
+		t.Errorf("Expected no applied migrations but got: %+v (err: %v)", applied, err)
+	}
+
+	status, _ = m.Migrations()
+	for _, migration := range status {
+		if migration.AppliedUtc == nil {
+			t.Errorf("Expected migration %d to be applied", migration.Version)
+		}
+	}
+
+	// existing data is kept
+	if count := store.GetItemsCount("sonarr", "missing"); count != 1 {
+		t.Errorf("Expected existing media item to be kept but got: %d items", count)
+	}
+
+	// databases migrated by a newer version are refused
+	latest := migrations[len(migrations)-1].version
+	store.(*sqlStore).db.Create(&SchemaVersion{Version: latest + 1, Name: "future", AppliedUtc: time.Now().UTC()})
+
+	if _, err := m.Migrate(); err == nil {
+		t.Error("Expected error migrating a database with a newer schema version")
+	}
+}
+
+func TestMigrateSchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wantarr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewSQLiteStore(filepath.Join(dir, "vault.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if _, err := store.(Migrator).Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the migrated schema must have a column for every field of the latest structs
+	db := store.(*sqlStore).db
+	for _, model := range []interface{}{&MediaItem{}, &SearchHistory{}, &SearchRun{}} {
+		scope := db.NewScope(model)
+		for _, field := range scope.GetModelStruct().StructFields {
+			if !db.Dialect().HasColumn(scope.TableName(), field.DBName) {
+				t.Errorf("Expected column %s.%s to be created by migrations", scope.TableName(), field.DBName)
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+func (s *sqlStore) AddSearchRun(run *SearchRun) error {
+	if err := s.db.Create(run).Error; err != nil {
+		return errors.Wrap(err, "failed inserting search run")
+	}
+
+	return nil
+}
+
+func (s *sqlStore) UpdateSearchRun(run *SearchRun) error {
+	if err := s.db.Save(run).Error; err != nil {
+		return errors.Wrapf(err, "failed updating search run: %d", run.Id)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) GetSearchRuns(pvrName string, wantedType string, status string, limit int) ([]SearchRun, error) {
+	var runs []SearchRun
+
+	query := s.filterQuery(pvrName, wantedType)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Order("id desc").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, errors.Wrap(err, "failed querying for search runs")
+	}
+
+	return runs, nil
+}
+
+/* Private */
+
+func (s *sqlStore) filterQuery(pvrName string, wantedType string) *gorm.DB {
+	query := s.db
+	if pvrName != "" {
+		query = query.Where("pvr_name = ?", pvrName)
+	}
+	if wantedType != "" {
+		query = query.Where("wanted_type = ?", wantedType)
+	}
+
+	return query
+}
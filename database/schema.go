@@ -6,6 +6,35 @@ type MediaItem struct {
 	Id                int    `gorm:"primary_key;auto_increment:false"`
 	PvrName           string `gorm:"primary_key"`
 	WantedType        string `gorm:"primary_key"`
+	SeriesId          int
 	AirDateUtc        time.Time
 	LastSearchDateUtc *time.Time `gorm:"null"`
 }
+
+type SearchHistory struct {
+	Id         uint      `gorm:"primary_key" json:"id"`
+	RunId      uint      `json:"run_id"`
+	PvrName    string    `json:"pvr"`
+	WantedType string    `json:"wanted_type"`
+	CommandId  int       `json:"command_id"`
+	Status     string    `json:"status"`
+	Message    string    `gorm:"type:text" json:"message"`
+	ItemsCount int       `json:"items_count"`
+	StartedUtc time.Time `json:"started"`
+	EndedUtc   time.Time `json:"ended"`
+}
+
+type SearchRun struct {
+	Id            uint       `gorm:"primary_key" json:"id"`
+	PvrName       string     `json:"pvr"`
+	WantedType    string     `json:"wanted_type"`
+	Status        string     `json:"status"`
+	Reason        string     `gorm:"type:text" json:"reason,omitempty"`
+	SearchedItems int        `json:"searched_items"`
+	Batches       int        `json:"batches"`
+	FailedBatches int        `json:"failed_batches"`
+	StartedUtc    time.Time  `json:"started"`
+	EndedUtc      *time.Time `gorm:"null" json:"ended,omitempty"`
+	Host          string     `json:"host,omitempty"`
+	Pid           int        `json:"pid,omitempty"`
+}
@@ -5,9 +5,9 @@ import (
 	"github.com/pkg/errors"
 )
 
-func SetMediaItems(pvrName string, wantedType string, mediaItems []pvr.MediaItem) error {
+func (s *sqlStore) SetMediaItems(pvrName string, wantedType string, mediaItems []pvr.MediaItem) error {
 	// begin transaction
-	tx := db.Begin()
+	tx := s.db.Begin()
 
 	// bulk insert/update items
 	for _, item := range mediaItems {
@@ -15,6 +15,7 @@ func SetMediaItems(pvrName string, wantedType string, mediaItems []pvr.MediaItem
 		mediaItem := MediaItem{
 			PvrName:    pvrName,
 			WantedType: wantedType,
+			SeriesId:   item.SeriesId,
 			AirDateUtc: item.AirDateUtc,
 		}
 
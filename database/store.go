@@ -0,0 +1,77 @@
+package database
+
+import (
+	"github.com/l3uddz/wantarr/pvr"
+	stringutils "github.com/l3uddz/wantarr/utils/strings"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MemoryDatabase is the database path used to keep state in memory only
+	MemoryDatabase = ":memory:"
+)
+
+/* Interfaces */
+
+// Store persists wanted media items, search runs and search history
+type Store interface {
+	// media items
+	GetItemsCount(pvrName string, wantedType string) int
+	GetMediaItems(pvrName string, wantedType string, excludeFuture bool) ([]MediaItem, error)
+	SetMediaItems(pvrName string, wantedType string, mediaItems []pvr.MediaItem) error
+	DeleteMissingItems(pvrName string, wantedType string, newMediaItems []pvr.MediaItem) (int, error)
+	DeleteMediaItems(pvrName string, wantedType string, itemIds []int) (int, error)
+	DeleteSeriesMediaItems(pvrName string, seriesId int) (int, error)
+
+	// search history
+	AddSearchHistory(runId uint, pvrName string, wantedType string, itemsCount int, command *pvr.SearchCommand) error
+	GetSearchHistory(pvrName string, wantedType string, limit int) ([]SearchHistory, error)
+
+	// search runs
+	AddSearchRun(run *SearchRun) error
+	UpdateSearchRun(run *SearchRun) error
+	GetSearchRuns(pvrName string, wantedType string, status string, limit int) ([]SearchRun, error)
+
+	Close() error
+}
+
+/* Public */
+
+// Open returns the store for the dsn when set, otherwise the database file or an in-memory store for MemoryDatabase.
+// Pending schema migrations are applied when migrate is set.
+func Open(databaseFilePath string, dsn string, migrate bool) (Store, error) {
+	store, err := open(databaseFilePath, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// migrate schema
+	if m, ok := store.(Migrator); ok && migrate {
+		if _, err := m.Migrate(); err != nil {
+			_ = store.Close()
+			return nil, errors.WithMessage(err, "failed migrating database schema")
+		}
+	}
+
+	return store, nil
+}
+
+/* Private */
+
+func open(databaseFilePath string, dsn string) (Store, error) {
+	if dsn != "" {
+		// show log
+		log.Infof("Using %s = %q", stringutils.StringLeftJust("DATABASE", " ", 10), RedactDSN(dsn))
+
+		return NewSQLStore(dsn)
+	}
+
+	// show log
+	log.Infof("Using %s = %q", stringutils.StringLeftJust("DATABASE", " ", 10), databaseFilePath)
+
+	if databaseFilePath == MemoryDatabase {
+		return NewMemoryStore(), nil
+	}
+
+	return NewSQLiteStore(databaseFilePath)
+}
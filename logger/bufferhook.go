@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+type bufferHook struct {
+	level   logrus.Level
+	entries []*logrus.Entry
+	mtx     sync.Mutex
+}
+
+func newBufferHook(level logrus.Level) *bufferHook {
+	return &bufferHook{level: level}
+}
+
+func (hook *bufferHook) Levels() []logrus.Level {
+	return logrus.AllLevels[:hook.level+1]
+}
+
+func (hook *bufferHook) Fire(entry *logrus.Entry) error {
+	// entries are re-used by logrus once fired, so keep a copy
+	data := make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+
+	hook.mtx.Lock()
+	hook.entries = append(hook.entries, &logrus.Entry{
+		Logger:  entry.Logger,
+		Data:    data,
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Message: entry.Message,
+	})
+	hook.mtx.Unlock()
+
+	return nil
+}
+
+func (hook *bufferHook) replay(hooks logrus.LevelHooks) {
+	hook.mtx.Lock()
+	defer hook.mtx.Unlock()
+
+	for _, entry := range hook.entries {
+		// write to console
+		if b, err := entry.Logger.Formatter.Format(entry); err == nil {
+			_, _ = entry.Logger.Out.Write(b)
+		}
+
+		_ = hooks.Fire(entry.Level, entry)
+	}
+
+	hook.entries = nil
+}
@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type JSONFormatter struct {
+	formatter logrus.JSONFormatter
+}
+
+func (f *JSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	// trim the padding applied to prefixes for the text formatter
+	if prefix, ok := entry.Data["prefix"].(string); ok {
+		data := make(logrus.Fields, len(entry.Data))
+		for k, v := range entry.Data {
+			data[k] = v
+		}
+		data["prefix"] = strings.TrimSpace(prefix)
+
+		e := *entry
+		e.Data = data
+		entry = &e
+	}
+
+	f.formatter.TimestampFormat = time.RFC3339Nano
+	return f.formatter.Format(entry)
+}
@@ -1,7 +1,11 @@
 package logger
 
 import (
+	"fmt"
 	"github.com/l3uddz/wantarr/utils/strings"
+	"io"
+	"io/ioutil"
+	"os"
 	"runtime"
 
 	"github.com/pkg/errors"
@@ -9,66 +13,182 @@ import (
 	prefixed "github.com/x-cray/logrus-prefixed-formatter"
 )
 
+const (
+	// Log formats
+	FormatText = "text"
+	FormatJson = "json"
+)
+
 var (
 	prefixLen       = 14
+	loggingLevel    logrus.Level
 	loggingFilePath string
+	consoleFormat   string
+	fileFormat      string
+	fileFormatter   logrus.Formatter
+	fileHooks       []fileHook
+	earlyHook       *bufferHook
 )
 
-/* Public */
+/* Interface */
 
-func Init(logLevel int, logFilePath string) error {
-	var useLevel logrus.Level
+type fileHook interface {
+	logrus.Hook
+	SetFormatter(logrus.Formatter)
+}
+
+/* Public */
 
+// Init sets up the log level and formats, entries are buffered until Configure sets up the destinations.
+func Init(logLevel int, logFilePath string, logFormat string, logFileFormat string) error {
 	// determine logging level
 	switch logLevel {
 	case 0:
-		useLevel = logrus.InfoLevel
+		loggingLevel = logrus.InfoLevel
 	case 1:
-		useLevel = logrus.DebugLevel
+		loggingLevel = logrus.DebugLevel
 	default:
-		useLevel = logrus.TraceLevel
+		loggingLevel = logrus.TraceLevel
 	}
 
-	// set rotating file hook
-	fileLogFormatter := &prefixed.TextFormatter{}
-	fileLogFormatter.FullTimestamp = true
-	fileLogFormatter.QuoteEmptyFields = true
-	fileLogFormatter.DisableColors = true
-	fileLogFormatter.ForceFormatting = true
-
-	rotateFileHook, err := NewRotateFileHook(RotateFileConfig{
-		Filename:   logFilePath,
-		MaxSize:    5,
-		MaxBackups: 10,
-		MaxAge:     90,
-		Level:      useLevel,
-		Formatter:  fileLogFormatter,
+	// set formatters
+	if err := SetFormat(logFormat, logFileFormat); err != nil {
+		return err
+	}
+
+	// buffer entries until configured (using default settings when exiting beforehand)
+	earlyHook = newBufferHook(loggingLevel)
+	logrus.AddHook(earlyHook)
+	logrus.SetOutput(ioutil.Discard)
+	logrus.RegisterExitHandler(func() {
+		if earlyHook != nil {
+			_ = Configure(Settings{})
+		}
 	})
 
-	if err != nil {
-		logrus.WithError(err).Errorf("Failed initializing rotating file log to %q", logFilePath)
-		return errors.Wrap(err, "failed initializing rotating file hook")
+	// set logging level
+	logrus.SetLevel(loggingLevel)
+
+	// set globals
+	loggingFilePath = logFilePath
+
+	return nil
+}
+
+// Configure sets up the console output, log files and syslog, then writes any entries logged since Init to them.
+func Configure(settings Settings) error {
+	var console io.Writer
+	hooks := make(logrus.LevelHooks)
+
+	// determine console output
+	switch settings.Console {
+	case "", ConsoleStderr:
+		console = os.Stderr
+	case ConsoleStdout:
+		console = os.Stdout
+	case ConsoleNone:
+		console = ioutil.Discard
+	default:
+		return fmt.Errorf("unsupported console log output: %q (supported: %s, %s, %s)", settings.Console,
+			ConsoleStderr, ConsoleStdout, ConsoleNone)
+	}
+
+	// set rotating file hooks
+	var files []fileHook
+	if !settings.File.Disabled && loggingFilePath != "" {
+		cfg := RotateFileConfig{
+			Filename:   loggingFilePath,
+			MaxSize:    settings.File.MaxSize,
+			MaxBackups: settings.File.MaxBackups,
+			MaxAge:     settings.File.MaxAge,
+			Level:      loggingLevel,
+			Formatter:  fileFormatter,
+		}
+
+		if cfg.MaxSize <= 0 {
+			cfg.MaxSize = defaultFileMaxSize
+		}
+		if cfg.MaxBackups <= 0 {
+			cfg.MaxBackups = defaultFileMaxBackups
+		}
+		if cfg.MaxAge <= 0 {
+			cfg.MaxAge = defaultFileMaxAge
+		}
+
+		rotateFileHook, err := NewRotateFileHook(cfg)
+		if err != nil {
+			logrus.WithError(err).Errorf("Failed initializing rotating file log to %q", loggingFilePath)
+			return errors.Wrap(err, "failed initializing rotating file hook")
+		}
+		hooks.Add(rotateFileHook)
+		files = append(files, rotateFileHook.(fileHook))
+
+		if settings.File.PerPvr {
+			pvrHook := NewPvrFileHook(cfg)
+			hooks.Add(pvrHook)
+			files = append(files, pvrHook)
+		}
+	}
+
+	// set syslog hook
+	if settings.Syslog.Enabled {
+		tag := settings.Syslog.Tag
+		if tag == "" {
+			tag = defaultSyslogTag
+		}
+
+		syslogHook, err := NewSyslogHook(settings.Syslog.Network, settings.Syslog.Address, tag, loggingLevel)
+		if err != nil {
+			logrus.WithError(err).Error("Failed initializing syslog")
+			return errors.Wrap(err, "failed initializing syslog hook")
+		}
+		hooks.Add(syslogHook)
+	}
+
+	// replace hooks, writing buffered entries to them
+	logrus.SetOutput(console)
+	logrus.StandardLogger().ReplaceHooks(hooks)
+	fileHooks = files
+
+	if earlyHook != nil {
+		earlyHook.replay(hooks)
+		earlyHook = nil
+	}
+
+	return nil
+}
+
+// SetFormat sets the console and file log formats, an empty file format uses the console format.
+func SetFormat(logFormat string, logFileFormat string) error {
+	if logFormat == "" {
+		logFormat = FormatText
+	}
+
+	if logFileFormat == "" {
+		logFileFormat = logFormat
 	}
-	logrus.AddHook(rotateFileHook)
 
 	// set console formatter
-	logFormatter := &prefixed.TextFormatter{}
-	logFormatter.FullTimestamp = true
-	logFormatter.QuoteEmptyFields = true
-	logFormatter.ForceFormatting = true
-
-	if runtime.GOOS == "windows" {
-		// disable colors on windows
-		logFormatter.DisableColors = true
+	logFormatter, err := newFormatter(logFormat, runtime.GOOS == "windows")
+	if err != nil {
+		return errors.WithMessage(err, "failed initializing console log formatter")
 	}
 
-	logrus.SetFormatter(logFormatter)
+	// set file formatter
+	fileLogFormatter, err := newFormatter(logFileFormat, true)
+	if err != nil {
+		return errors.WithMessage(err, "failed initializing file log formatter")
+	}
 
-	// set logging level
-	logrus.SetLevel(useLevel)
+	logrus.SetFormatter(logFormatter)
+	for _, hook := range fileHooks {
+		hook.SetFormatter(fileLogFormatter)
+	}
 
 	// set globals
-	loggingFilePath = logFilePath
+	consoleFormat = logFormat
+	fileFormat = logFileFormat
+	fileFormatter = fileLogFormatter
 
 	return nil
 }
@@ -78,7 +198,11 @@ func ShowUsing() {
 
 	log.Infof("Using %s = %s", strings.StringLeftJust("LOG_LEVEL", " ", 10),
 		logrus.GetLevel().String())
-	log.Infof("Using %s = %q", strings.StringLeftJust("LOG", " ", 10), loggingFilePath)
+	if len(fileHooks) > 0 {
+		log.Infof("Using %s = %q", strings.StringLeftJust("LOG", " ", 10), loggingFilePath)
+	}
+	log.Infof("Using %s = %s (file: %s)", strings.StringLeftJust("LOG_FORMAT", " ", 10), consoleFormat,
+		fileFormat)
 }
 
 func GetLogger(prefix string) *logrus.Entry {
@@ -88,3 +212,23 @@ func GetLogger(prefix string) *logrus.Entry {
 
 	return logrus.WithFields(logrus.Fields{"prefix": strings.StringLeftJust(prefix, " ", prefixLen)})
 }
+
+/* Private */
+
+func newFormatter(format string, disableColors bool) (logrus.Formatter, error) {
+	switch format {
+	case FormatText:
+		f := &prefixed.TextFormatter{}
+		f.FullTimestamp = true
+		f.QuoteEmptyFields = true
+		f.DisableColors = disableColors
+		f.ForceFormatting = true
+		return f, nil
+	case FormatJson:
+		return &JSONFormatter{}, nil
+	default:
+		break
+	}
+
+	return nil, fmt.Errorf("unsupported log format: %q (supported: %s, %s)", format, FormatText, FormatJson)
+}
@@ -2,6 +2,9 @@ package logger
 
 import (
 	"io"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/natefinch/lumberjack"
 	"github.com/sirupsen/logrus"
@@ -21,6 +24,12 @@ type RotateFileHook struct {
 	logWriter io.Writer
 }
 
+type PvrFileHook struct {
+	Config RotateFileConfig
+	hooks  map[string]*RotateFileHook
+	mtx    sync.Mutex
+}
+
 func NewRotateFileHook(config RotateFileConfig) (logrus.Hook, error) {
 	hook := RotateFileHook{
 		Config: config,
@@ -48,3 +57,51 @@ func (hook *RotateFileHook) Fire(entry *logrus.Entry) (err error) {
 	_, _ = hook.logWriter.Write(b)
 	return nil
 }
+
+func (hook *RotateFileHook) SetFormatter(formatter logrus.Formatter) {
+	hook.Config.Formatter = formatter
+}
+
+// NewPvrFileHook writes entries with a pvr field to a rotating file per pvr, e.g. activity.sonarr.log
+func NewPvrFileHook(config RotateFileConfig) *PvrFileHook {
+	return &PvrFileHook{
+		Config: config,
+		hooks:  make(map[string]*RotateFileHook),
+	}
+}
+
+func (hook *PvrFileHook) Levels() []logrus.Level {
+	return logrus.AllLevels[:hook.Config.Level+1]
+}
+
+func (hook *PvrFileHook) Fire(entry *logrus.Entry) error {
+	pvrName, ok := entry.Data["pvr"].(string)
+	if !ok || pvrName == "" {
+		return nil
+	}
+
+	hook.mtx.Lock()
+	pvrHook, ok := hook.hooks[pvrName]
+	if !ok {
+		config := hook.Config
+		ext := filepath.Ext(config.Filename)
+		config.Filename = strings.TrimSuffix(config.Filename, ext) + "." + pvrName + ext
+
+		h, _ := NewRotateFileHook(config)
+		pvrHook = h.(*RotateFileHook)
+		hook.hooks[pvrName] = pvrHook
+	}
+	hook.mtx.Unlock()
+
+	return pvrHook.Fire(entry)
+}
+
+func (hook *PvrFileHook) SetFormatter(formatter logrus.Formatter) {
+	hook.mtx.Lock()
+	defer hook.mtx.Unlock()
+
+	hook.Config.Formatter = formatter
+	for _, h := range hook.hooks {
+		h.SetFormatter(formatter)
+	}
+}
@@ -0,0 +1,38 @@
+package logger
+
+const (
+	// Console outputs
+	ConsoleStderr = "stderr"
+	ConsoleStdout = "stdout"
+	ConsoleNone   = "none"
+)
+
+var (
+	defaultFileMaxSize    = 5
+	defaultFileMaxBackups = 10
+	defaultFileMaxAge     = 90
+	defaultSyslogTag      = "wantarr"
+)
+
+/* Structs */
+
+type Settings struct {
+	Console string
+	File    FileSettings
+	Syslog  SyslogSettings
+}
+
+type FileSettings struct {
+	Disabled   bool
+	MaxSize    int
+	MaxBackups int
+	MaxAge     int
+	PerPvr     bool
+}
+
+type SyslogSettings struct {
+	Enabled bool
+	Network string
+	Address string
+	Tag     string
+}
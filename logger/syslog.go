@@ -0,0 +1,63 @@
+//go:build !windows && !nacl && !plan9
+// +build !windows,!nacl,!plan9
+
+package logger
+
+import (
+	"log/syslog"
+
+	"github.com/sirupsen/logrus"
+	prefixed "github.com/x-cray/logrus-prefixed-formatter"
+)
+
+type SyslogHook struct {
+	level     logrus.Level
+	formatter logrus.Formatter
+	writer    *syslog.Writer
+}
+
+// NewSyslogHook connects to syslog, an empty network and address uses the local syslog (and journald) socket
+func NewSyslogHook(network string, address string, tag string, level logrus.Level) (logrus.Hook, error) {
+	writer, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	// syslog timestamps entries itself
+	formatter := &prefixed.TextFormatter{}
+	formatter.DisableTimestamp = true
+	formatter.DisableColors = true
+	formatter.QuoteEmptyFields = true
+	formatter.ForceFormatting = true
+
+	return &SyslogHook{
+		level:     level,
+		formatter: formatter,
+		writer:    writer,
+	}, nil
+}
+
+func (hook *SyslogHook) Levels() []logrus.Level {
+	return logrus.AllLevels[:hook.level+1]
+}
+
+func (hook *SyslogHook) Fire(entry *logrus.Entry) error {
+	b, err := hook.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	line := string(b)
+	switch entry.Level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return hook.writer.Crit(line)
+	case logrus.ErrorLevel:
+		return hook.writer.Err(line)
+	case logrus.WarnLevel:
+		return hook.writer.Warning(line)
+	case logrus.InfoLevel:
+		return hook.writer.Info(line)
+	default:
+		return hook.writer.Debug(line)
+	}
+}
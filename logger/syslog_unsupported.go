@@ -0,0 +1,14 @@
+//go:build windows || nacl || plan9
+// +build windows nacl plan9
+
+package logger
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+func NewSyslogHook(network string, address string, tag string, level logrus.Level) (logrus.Hook, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "wantarr"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	// ItemsSearched counts media items that were searched
+	ItemsSearched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "items_searched_total",
+		Help:      "Number of media items searched.",
+	}, []string{"pvr", "wanted_type"})
+
+	// BatchesSent counts search batches sent to a pvr
+	BatchesSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "batches_sent_total",
+		Help:      "Number of search batches sent.",
+	}, []string{"pvr", "wanted_type"})
+
+	// CommandDuration observes how long search commands took to finish
+	CommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "command_duration_seconds",
+		Help:      "Duration of search commands.",
+		Buckets:   []float64{5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600},
+	}, []string{"pvr", "wanted_type", "status"})
+
+	// CommandFailures counts search commands that did not complete
+	CommandFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "command_failures_total",
+		Help:      "Number of search commands that did not complete.",
+	}, []string{"pvr", "wanted_type", "status"})
+
+	// QueueItems tracks the queue observed by the queue monitor
+	QueueItems = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_items",
+		Help:      "Number of items in the pvr queue by state, as observed by the queue monitor.",
+	}, []string{"pvr", "state"})
+
+	// CachedItems tracks wanted media items stored in the database
+	CachedItems = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cached_items",
+		Help:      "Number of wanted media items cached in the database.",
+	}, []string{"pvr", "wanted_type"})
+
+	// EligibleItems tracks wanted media items eligible for search at the start of a run
+	EligibleItems = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "eligible_items",
+		Help:      "Number of wanted media items eligible for search at the start of the last run.",
+	}, []string{"pvr", "wanted_type"})
+)
+
+/* Private */
+
+func init() {
+	registry.MustRegister(
+		ItemsSearched,
+		BatchesSent,
+		CommandDuration,
+		CommandFailures,
+		QueueItems,
+		CachedItems,
+		EligibleItems,
+	)
+}
+
+/* Public */
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+func WriteTextfile(filePath string) error {
+	if err := prometheus.WriteToTextfile(filePath, registry); err != nil {
+		return errors.Wrapf(err, "failed writing metrics to textfile: %q", filePath)
+	}
+
+	return nil
+}
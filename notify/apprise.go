@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"context"
+	"strings"
+)
+
+/* Structs */
+
+type Apprise struct {
+	url string
+}
+
+type AppriseMessage struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Type  string `json:"type"`
+}
+
+/* Initializer */
+
+func NewApprise(url string) *Apprise {
+	return &Apprise{url: url}
+}
+
+/* Interface Implements */
+
+func (n *Apprise) Send(ctx context.Context, summary *Summary) error {
+	messageType := "failure"
+	switch strings.ToLower(summary.Run.Status) {
+	case "completed":
+		messageType = "success"
+	case "aborted", "interrupted":
+		messageType = "warning"
+	}
+
+	return postJSON(ctx, n.url, &AppriseMessage{
+		Title: summary.Title,
+		Body:  summary.Message,
+		Type:  messageType,
+	})
+}
@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"context"
+	"strings"
+)
+
+/* Structs */
+
+type Discord struct {
+	url string
+}
+
+type DiscordMessage struct {
+	Username string         `json:"username"`
+	Embeds   []DiscordEmbed `json:"embeds"`
+}
+
+type DiscordEmbed struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Color       int    `json:"color"`
+	Timestamp   string `json:"timestamp,omitempty"`
+}
+
+/* Initializer */
+
+func NewDiscord(url string) *Discord {
+	return &Discord{url: url}
+}
+
+/* Interface Implements */
+
+func (n *Discord) Send(ctx context.Context, summary *Summary) error {
+	embed := DiscordEmbed{
+		Title:       summary.Title,
+		Description: summary.Message,
+		Color:       statusColor(summary.Run.Status),
+	}
+
+	if summary.Run.EndedUtc != nil {
+		embed.Timestamp = summary.Run.EndedUtc.Format("2006-01-02T15:04:05Z07:00")
+	}
+
+	return postJSON(ctx, n.url, &DiscordMessage{
+		Username: "wantarr",
+		Embeds:   []DiscordEmbed{embed},
+	})
+}
+
+/* Private */
+
+func statusColor(status string) int {
+	switch strings.ToLower(status) {
+	case "completed":
+		return 0x2ecc71
+	case "aborted", "interrupted":
+		return 0xf1c40f
+	default:
+		return 0xe74c3c
+	}
+}
@@ -0,0 +1,213 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/imroc/req"
+	"github.com/jpillora/backoff"
+	"github.com/l3uddz/wantarr/config"
+	"github.com/l3uddz/wantarr/database"
+	"github.com/l3uddz/wantarr/logger"
+	"github.com/l3uddz/wantarr/utils/lists"
+	"github.com/l3uddz/wantarr/utils/web"
+	"github.com/pkg/errors"
+	"github.com/tommysolsen/capitalise"
+)
+
+var (
+	// Logging
+	log = logger.GetLogger("notify")
+
+	notifyDefaultTimeout = 30
+	notifyDefaultRetry   = web.Retry{
+		MaxAttempts: 3,
+		RetryableStatusCodes: []int{
+			429,
+			500,
+			502,
+			503,
+			504,
+		},
+		Backoff: backoff.Backoff{
+			Jitter: true,
+			Min:    1 * time.Second,
+			Max:    10 * time.Second,
+		},
+	}
+)
+
+const (
+	// Notification types
+	TypeWebhook = "webhook"
+	TypeDiscord = "discord"
+	TypeSlack   = "slack"
+	TypeApprise = "apprise"
+)
+
+/* Interface */
+
+type Interface interface {
+	Send(context.Context, *Summary) error
+}
+
+/* Structs */
+
+type Summary struct {
+	Title   string              `json:"title"`
+	Message string              `json:"message"`
+	Run     *database.SearchRun `json:"run"`
+}
+
+/* Public */
+
+func Get(n config.Notification) (Interface, error) {
+	notifyType, notifyUrl, err := parseURL(n.Type, n.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch notifyType {
+	case TypeWebhook:
+		return NewWebhook(notifyUrl), nil
+	case TypeDiscord:
+		return NewDiscord(notifyUrl), nil
+	case TypeSlack:
+		return NewSlack(notifyUrl), nil
+	case TypeApprise:
+		return NewApprise(notifyUrl), nil
+	default:
+		break
+	}
+
+	return nil, fmt.Errorf("unsupported notification type: %s", notifyType)
+}
+
+func NewSummary(run *database.SearchRun) *Summary {
+	title := fmt.Sprintf("%s %s search %s", capitalise.First(run.PvrName), run.WantedType, run.Status)
+
+	lines := []string{
+		fmt.Sprintf("Searched items: %d", run.SearchedItems),
+		fmt.Sprintf("Batches: %d", run.Batches),
+		fmt.Sprintf("Failed batches: %d", run.FailedBatches),
+	}
+
+	if run.EndedUtc != nil {
+		lines = append(lines, fmt.Sprintf("Duration: %s", run.EndedUtc.Sub(run.StartedUtc).Round(time.Second)))
+	}
+
+	if run.Reason != "" {
+		lines = append(lines, fmt.Sprintf("Reason: %s", run.Reason))
+	}
+
+	return &Summary{
+		Title:   title,
+		Message: strings.Join(lines, "\n"),
+		Run:     run,
+	}
+}
+
+// Send posts the run summary to every notification interested in the run status, failures are only logged.
+func Send(notifications []config.Notification, run *database.SearchRun) {
+	summary := NewSummary(run)
+
+	for pos, n := range notifications {
+		l := log.WithField("notification", pos)
+
+		// skip notifications not interested in this status
+		if len(n.Statuses) > 0 && !lists.StringListContains(n.Statuses, run.Status, false) {
+			l.Tracef("Skipping notification for run status: %s", run.Status)
+			continue
+		}
+
+		notifier, err := Get(n)
+		if err != nil {
+			l.WithError(err).Error("Failed loading notification")
+			continue
+		}
+
+		// send with a fresh context so interrupted runs are still notified
+		ctx, cancel := context.WithTimeout(context.Background(),
+			time.Duration(notifyDefaultTimeout*int(notifyDefaultRetry.MaxAttempts))*time.Second)
+		err = notifier.Send(ctx, summary)
+		cancel()
+
+		if err != nil {
+			l.WithError(err).Error("Failed sending notification")
+			continue
+		}
+
+		l.Debug("Sent notification")
+	}
+}
+
+/* Private */
+
+// parseURL determines the notification type and http url, translating apprise style urls, e.g. discord://id/token
+func parseURL(notifyType string, notifyUrl string) (string, string, error) {
+	u, err := url.Parse(notifyUrl)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed parsing notification url: %q", notifyUrl)
+	}
+
+	path := strings.Trim(u.Path, "/")
+	notifyType = strings.ToLower(notifyType)
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if notifyType == "" {
+			notifyType = TypeWebhook
+		}
+		return notifyType, notifyUrl, nil
+	case "discord":
+		// discord://webhook_id/webhook_token
+		if u.Host == "" || path == "" {
+			return "", "", fmt.Errorf("expected discord://webhook_id/webhook_token: %q", notifyUrl)
+		}
+		return TypeDiscord, fmt.Sprintf("https://discord.com/api/webhooks/%s/%s", u.Host, path), nil
+	case "slack":
+		// slack://token_a/token_b/token_c
+		if u.Host == "" || len(strings.Split(path, "/")) != 2 {
+			return "", "", fmt.Errorf("expected slack://token_a/token_b/token_c: %q", notifyUrl)
+		}
+		return TypeSlack, fmt.Sprintf("https://hooks.slack.com/services/%s/%s", u.Host, path), nil
+	case "json", "jsons":
+		return TypeWebhook, httpURL(u, u.Scheme == "jsons"), nil
+	case "apprise", "apprises":
+		return TypeApprise, httpURL(u, u.Scheme == "apprises"), nil
+	default:
+		break
+	}
+
+	return "", "", fmt.Errorf("unsupported notification url scheme: %q", u.Scheme)
+}
+
+func httpURL(u *url.URL, secure bool) string {
+	h := *u
+	h.Scheme = "http"
+	if secure {
+		h.Scheme = "https"
+	}
+
+	return h.String()
+}
+
+func postJSON(ctx context.Context, notifyUrl string, payload interface{}) error {
+	// send request
+	resp, err := web.GetResponse(ctx, web.POST, notifyUrl, notifyDefaultTimeout, req.BodyJSON(payload),
+		&notifyDefaultRetry)
+	if err != nil {
+		return errors.WithMessage(err, "failed sending notification request")
+	}
+	defer resp.Response().Body.Close()
+
+	// validate response
+	if resp.Response().StatusCode < 200 || resp.Response().StatusCode > 299 {
+		return fmt.Errorf("failed validating notification response: %s", resp.Response().Status)
+	}
+
+	return nil
+}
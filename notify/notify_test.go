@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"testing"
+)
+
+/* Test Notification URLs */
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		notifyType   string
+		notifyUrl    string
+		expectedType string
+		expectedUrl  string
+		expectError  bool
+	}{
+		{"", "https://example.com/hook", TypeWebhook, "https://example.com/hook", false},
+		{"discord", "https://discord.com/api/webhooks/1/abc", TypeDiscord, "https://discord.com/api/webhooks/1/abc", false},
+		{"", "discord://1/abc", TypeDiscord, "https://discord.com/api/webhooks/1/abc", false},
+		{"", "slack://A/B/C", TypeSlack, "https://hooks.slack.com/services/A/B/C", false},
+		{"", "json://localhost:8080/hook", TypeWebhook, "http://localhost:8080/hook", false},
+		{"", "jsons://example.com/hook", TypeWebhook, "https://example.com/hook", false},
+		{"", "apprise://localhost:8000/notify/key", TypeApprise, "http://localhost:8000/notify/key", false},
+		{"", "discord://1", "", "", true},
+		{"", "slack://A/B", "", "", true},
+		{"", "pushover://user/token", "", "", true},
+	}
+
+	for _, tc := range tests {
+		notifyType, notifyUrl, err := parseURL(tc.notifyType, tc.notifyUrl)
+		if tc.expectError {
+			if err == nil {
+				t.Errorf("Expected error for %q but got: %s %s", tc.notifyUrl, notifyType, notifyUrl)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tc.notifyUrl, err)
+			continue
+		}
+
+		if notifyType != tc.expectedType || notifyUrl != tc.expectedUrl {
+			t.Errorf("Expected %s %q for %q but got: %s %q", tc.expectedType, tc.expectedUrl, tc.notifyUrl,
+				notifyType, notifyUrl)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+)
+
+/* Structs */
+
+type Slack struct {
+	url string
+}
+
+type SlackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []SlackAttachment `json:"attachments"`
+}
+
+type SlackAttachment struct {
+	Color string `json:"color"`
+	Text  string `json:"text"`
+}
+
+/* Initializer */
+
+func NewSlack(url string) *Slack {
+	return &Slack{url: url}
+}
+
+/* Interface Implements */
+
+func (n *Slack) Send(ctx context.Context, summary *Summary) error {
+	return postJSON(ctx, n.url, &SlackMessage{
+		Text: summary.Title,
+		Attachments: []SlackAttachment{
+			{
+				Color: fmt.Sprintf("#%06x", statusColor(summary.Run.Status)),
+				Text:  summary.Message,
+			},
+		},
+	})
+}
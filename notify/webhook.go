@@ -0,0 +1,23 @@
+package notify
+
+import (
+	"context"
+)
+
+/* Structs */
+
+type Webhook struct {
+	url string
+}
+
+/* Initializer */
+
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url}
+}
+
+/* Interface Implements */
+
+func (n *Webhook) Send(ctx context.Context, summary *Summary) error {
+	return postJSON(ctx, n.url, summary)
+}
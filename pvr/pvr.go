@@ -1,10 +1,15 @@
 package pvr
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
+	"github.com/imroc/req"
 	"github.com/jpillora/backoff"
 	"github.com/l3uddz/wantarr/config"
 	"github.com/l3uddz/wantarr/utils/web"
+	"github.com/pkg/errors"
+	"net/http"
 	"strings"
 	"time"
 )
@@ -15,6 +20,9 @@ var (
 	pvrDefaultRetry    = web.Retry{
 		MaxAttempts: 6,
 		RetryableStatusCodes: []int{
+			429,
+			502,
+			503,
 			504,
 		},
 		Backoff: backoff.Backoff{
@@ -23,20 +31,90 @@ var (
 			Max:    10 * time.Second,
 		},
 	}
+	pvrDefaultCommandMaxDuration  = 30 * time.Minute
+	pvrDefaultCommandPollInterval = 10 * time.Second
+
+	// ErrCommandTimeout is returned when a command has not finished within its maximum duration
+	ErrCommandTimeout = errors.New("command exceeded maximum duration")
+	// ErrCommandAborted is returned when a command was aborted or cancelled by the pvr
+	ErrCommandAborted = errors.New("command was aborted")
+)
+
+const (
+	// CommandStatusTimedOut is the status given to commands that exceeded their maximum duration
+	CommandStatusTimedOut = "timedout"
+	// CommandStatusAbandoned is the status given to commands that were no longer monitored due to cancellation
+	CommandStatusAbandoned = "abandoned"
+
+	// Queue states
+	QueueStateDownloading   = "downloading"
+	QueueStateQueued        = "queued"
+	QueueStatePaused        = "paused"
+	QueueStateFailed        = "failed"
+	QueueStateImportPending = "import_pending"
+	QueueStateWarning       = "warning"
+	QueueStateOther         = "other"
+)
+
+var (
+	// QueueStates lists the states queue items are grouped into
+	QueueStates = []string{
+		QueueStateDownloading,
+		QueueStateQueued,
+		QueueStatePaused,
+		QueueStateFailed,
+		QueueStateImportPending,
+		QueueStateWarning,
+		QueueStateOther,
+	}
 )
 
 type MediaItem struct {
 	ItemId     int
+	SeriesId   int
 	AirDateUtc time.Time
 	LastSearch time.Time
 }
 
+type SearchCommand struct {
+	Id      int
+	Status  string
+	Message string
+	Started time.Time
+	Ended   time.Time
+}
+
+type Queue struct {
+	Size   int
+	States map[string]int
+}
+
+type DiskSpace struct {
+	Path       string
+	Label      string
+	FreeSpace  int64
+	TotalSpace int64
+}
+
+type RootFolder struct {
+	Path      string
+	FreeSpace int64
+}
+
+type DownloadClient struct {
+	Name     string
+	Enable   bool
+	Protocol string
+}
+
 type Interface interface {
-	Init() error
-	GetQueueSize() (int, error)
-	GetWantedMissing() ([]MediaItem, error)
-	GetWantedCutoff() ([]MediaItem, error)
-	SearchMediaItems([]int) (bool, error)
+	Init(context.Context) error
+	GetQueue(context.Context) (*Queue, error)
+	GetWantedMissing(context.Context) ([]MediaItem, error)
+	GetWantedCutoff(context.Context) ([]MediaItem, error)
+	SearchMediaItems(context.Context, []int) (*SearchCommand, error)
+	GetRootFolderSpace(context.Context) ([]DiskSpace, error)
+	GetDownloadClients(context.Context) ([]DownloadClient, error)
 }
 
 /* Public */
@@ -44,14 +122,221 @@ type Interface interface {
 func Get(pvrName string, pvrType string, pvrConfig *config.Pvr) (Interface, error) {
 	switch strings.ToLower(pvrType) {
 	case "sonarr_v3":
-		return NewSonarrV3(pvrName, pvrConfig), nil
+		p, err := NewSonarrV3(pvrName, pvrConfig)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
 	case "radarr_v2":
-		return NewRadarrV2(pvrName, pvrConfig), nil
+		p, err := NewRadarrV2(pvrName, pvrConfig)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
 	case "radarr_v3":
-		return NewRadarrV3(pvrName, pvrConfig), nil
+		p, err := NewRadarrV3(pvrName, pvrConfig)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
 	default:
 		break
 	}
 
 	return nil, fmt.Errorf("unsupported pvr type provided: %q", pvrType)
 }
+
+func NewQueue() *Queue {
+	return &Queue{
+		States: make(map[string]int),
+	}
+}
+
+// StatesSize returns the number of queue items in the provided states, or the queue size when no states are provided.
+func (q *Queue) StatesSize(states []string) int {
+	if len(states) == 0 {
+		return q.Size
+	}
+
+	size := 0
+	for _, state := range states {
+		size += q.States[strings.ToLower(state)]
+	}
+
+	return size
+}
+
+/* Private */
+
+func (q *Queue) add(status string, trackedDownloadStatus string, trackedDownloadState string) {
+	q.Size++
+	q.States[getQueueItemState(status, trackedDownloadStatus, trackedDownloadState)]++
+}
+
+func getQueueItemState(status string, trackedDownloadStatus string, trackedDownloadState string) string {
+	// tracked download state / status take precedence over the download client status
+	switch strings.ToLower(trackedDownloadState) {
+	case "failed", "failedpending":
+		return QueueStateFailed
+	case "importpending", "importing":
+		return QueueStateImportPending
+	}
+
+	switch strings.ToLower(trackedDownloadStatus) {
+	case "warning", "error":
+		return QueueStateWarning
+	}
+
+	switch strings.ToLower(status) {
+	case "downloading":
+		return QueueStateDownloading
+	case "queued", "delay":
+		return QueueStateQueued
+	case "paused":
+		return QueueStatePaused
+	case "failed":
+		return QueueStateFailed
+	case "completed":
+		return QueueStateImportPending
+	case "warning", "downloadclientunavailable":
+		return QueueStateWarning
+	default:
+		return QueueStateOther
+	}
+}
+
+func getRootFolderSpace(rootFolders []RootFolder, disks []DiskSpace) []DiskSpace {
+	spaces := make([]DiskSpace, 0, len(rootFolders))
+
+	for _, rootFolder := range rootFolders {
+		// find the disk with the longest path containing the root folder
+		var match *DiskSpace
+		for pos, disk := range disks {
+			if !isParentPath(disk.Path, rootFolder.Path) {
+				continue
+			}
+
+			if match == nil || len(disk.Path) > len(match.Path) {
+				match = &disks[pos]
+			}
+		}
+
+		// fallback to the free space reported for the root folder
+		if match == nil {
+			spaces = append(spaces, DiskSpace{
+				Path:      rootFolder.Path,
+				FreeSpace: rootFolder.FreeSpace,
+			})
+			continue
+		}
+
+		spaces = append(spaces, DiskSpace{
+			Path:       rootFolder.Path,
+			Label:      match.Path,
+			FreeSpace:  match.FreeSpace,
+			TotalSpace: match.TotalSpace,
+		})
+	}
+
+	return spaces
+}
+
+func isParentPath(parent string, child string) bool {
+	parent = strings.TrimRight(strings.ReplaceAll(parent, "\\", "/"), "/") + "/"
+	child = strings.TrimRight(strings.ReplaceAll(child, "\\", "/"), "/") + "/"
+
+	return strings.HasPrefix(strings.ToLower(child), strings.ToLower(parent))
+}
+
+func getRequestHeaders(pvrConfig *config.Pvr) req.Header {
+	headers := req.Header{}
+
+	// set extra headers
+	for header, value := range pvrConfig.Headers {
+		headers[http.CanonicalHeaderKey(header)] = value
+	}
+
+	// set basic auth
+	if pvrConfig.BasicAuth.Username != "" || pvrConfig.BasicAuth.Password != "" {
+		credentials := pvrConfig.BasicAuth.Username + ":" + pvrConfig.BasicAuth.Password
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+
+	headers["X-Api-Key"] = pvrConfig.ApiKey
+	return headers
+}
+
+func getApiUrl(pvrConfig *config.Pvr, defaultApiPath string) string {
+	// requests over a unix socket are sent to a placeholder host
+	baseUrl := pvrConfig.URL
+	if _, ok := web.UnixSocketPath(baseUrl); ok {
+		baseUrl = web.UnixSocketURL
+	}
+
+	apiPath := pvrConfig.APIPath
+	if apiPath == "" {
+		apiPath = defaultApiPath
+	}
+
+	return web.JoinURL(baseUrl, pvrConfig.URLBase, apiPath)
+}
+
+func getHttpClient(pvrConfig *config.Pvr) (*http.Client, error) {
+	socketPath, _ := web.UnixSocketPath(pvrConfig.URL)
+
+	client, err := web.NewClient(web.ClientOptions{
+		CAFile:             pvrConfig.TLS.CAFile,
+		InsecureSkipVerify: pvrConfig.TLS.InsecureSkipVerify,
+		CertFile:           pvrConfig.TLS.CertFile,
+		KeyFile:            pvrConfig.TLS.KeyFile,
+		Proxy:              pvrConfig.Proxy,
+		UnixSocket:         socketPath,
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed initializing http client")
+	}
+
+	return client, nil
+}
+
+func getTimeout(pvrConfig *config.Pvr) int {
+	if pvrConfig.HTTP.Timeout > 0 {
+		// timeouts are in whole seconds, rounding up
+		return int((pvrConfig.HTTP.Timeout + time.Second - 1) / time.Second)
+	}
+
+	return pvrDefaultTimeout
+}
+
+func getRetry(pvrConfig *config.Pvr) web.Retry {
+	retry := pvrDefaultRetry
+	if pvrConfig.HTTP.Retries != nil {
+		retry.MaxAttempts = float64(*pvrConfig.HTTP.Retries)
+	}
+
+	return retry
+}
+
+func getPageSize(pvrConfig *config.Pvr) int {
+	if pvrConfig.PageSize > 0 {
+		return pvrConfig.PageSize
+	}
+
+	return pvrDefaultPageSize
+}
+
+func getCommandPollInterval(pvrConfig *config.Pvr) time.Duration {
+	if pvrConfig.SearchCommand.PollInterval > 0 {
+		return pvrConfig.SearchCommand.PollInterval
+	}
+
+	return pvrDefaultCommandPollInterval
+}
+
+func getCommandMaxDuration(pvrConfig *config.Pvr) time.Duration {
+	if pvrConfig.SearchCommand.MaxDuration > 0 {
+		return pvrConfig.SearchCommand.MaxDuration
+	}
+
+	return pvrDefaultCommandMaxDuration
+}
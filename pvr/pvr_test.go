@@ -0,0 +1,180 @@
+package pvr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/l3uddz/wantarr/config"
+)
+
+/* Test Queue States */
+
+func TestQueueStatesSize(t *testing.T) {
+	// build queue
+	queue := NewQueue()
+	queue.add("Downloading", "Ok", "downloading")
+	queue.add("Completed", "Warning", "importPending")
+	queue.add("Downloading", "Warning", "downloading")
+	queue.add("Paused", "Ok", "")
+	queue.add("Failed", "Error", "failedPending")
+
+	tests := []struct {
+		states   []string
+		expected int
+	}{
+		{nil, 5},
+		{[]string{QueueStateDownloading}, 1},
+		{[]string{QueueStateDownloading, QueueStatePaused}, 2},
+		{[]string{QueueStateImportPending}, 1},
+		{[]string{QueueStateWarning}, 1},
+		{[]string{QueueStateFailed}, 1},
+		{[]string{QueueStateQueued}, 0},
+	}
+
+	for _, tc := range tests {
+		if size := queue.StatesSize(tc.states); size != tc.expected {
+			t.Errorf("Expected queue size %d for states %v but got: %d", tc.expected, tc.states, size)
+		}
+	}
+}
+
+/* Test Root Folder Space */
+
+func TestGetRootFolderSpace(t *testing.T) {
+	disks := []DiskSpace{
+		{Path: "/", FreeSpace: 10},
+		{Path: "/mnt/media", FreeSpace: 20},
+		{Path: "/mnt/media/tv", FreeSpace: 30},
+		{Path: "D:\\", FreeSpace: 40},
+	}
+	rootFolders := []RootFolder{
+		{Path: "/mnt/media/tv/", FreeSpace: 1},
+		{Path: "/mnt/media/movies", FreeSpace: 2},
+		{Path: "/mnt/mediabackup", FreeSpace: 3},
+		{Path: "d:\\TV", FreeSpace: 4},
+		{Path: "E:\\TV", FreeSpace: 5},
+	}
+
+	expected := []int64{30, 20, 10, 40, 5}
+
+	spaces := getRootFolderSpace(rootFolders, disks)
+	for pos, space := range spaces {
+		if space.FreeSpace != expected[pos] {
+			t.Errorf("Expected free space %d for root folder %q but got: %d", expected[pos], space.Path,
+				space.FreeSpace)
+		}
+	}
+}
+
+/* Test Request Headers */
+
+func TestGetRequestHeaders(t *testing.T) {
+	pvrConfig := &config.Pvr{
+		ApiKey: "key",
+		Headers: map[string]string{
+			"x-forwarded-user": "wantarr",
+			"x-api-key":        "ignored",
+		},
+		BasicAuth: config.BasicAuth{Username: "user", Password: "pass"},
+	}
+
+	expected := map[string]string{
+		"X-Api-Key":        "key",
+		"X-Forwarded-User": "wantarr",
+		"Authorization":    "Basic dXNlcjpwYXNz",
+	}
+
+	headers := getRequestHeaders(pvrConfig)
+	if len(headers) != len(expected) {
+		t.Errorf("Expected %d headers but got: %v", len(expected), headers)
+	}
+
+	for header, value := range expected {
+		if headers[header] != value {
+			t.Errorf("Expected header %s to be %q but got: %q", header, value, headers[header])
+		}
+	}
+}
+
+/* Test Api Url */
+
+func TestGetApiUrl(t *testing.T) {
+	tests := []struct {
+		pvrConfig config.Pvr
+		expected  string
+	}{
+		{config.Pvr{URL: "https://sonarr.domain.com"}, "https://sonarr.domain.com/api/v3"},
+		{config.Pvr{URL: "https://domain.com/", URLBase: "/sonarr"}, "https://domain.com/sonarr/api/v3"},
+		{config.Pvr{URL: "https://domain.com/apis", URLBase: "apis/"}, "https://domain.com/apis/apis/api/v3"},
+		{config.Pvr{URL: "https://sonarr.domain.com", APIPath: "/api"}, "https://sonarr.domain.com/api"},
+		{config.Pvr{URL: "unix:///var/run/sonarr.sock"}, "http://unix/api/v3"},
+		{config.Pvr{URL: "unix:///var/run/sonarr.sock", URLBase: "/sonarr"}, "http://unix/sonarr/api/v3"},
+	}
+
+	for _, tc := range tests {
+		pvrConfig := tc.pvrConfig
+		if apiUrl := getApiUrl(&pvrConfig, "/api/v3"); apiUrl != tc.expected {
+			t.Errorf("Expected api url %q for %+v but got: %q", tc.expected, tc.pvrConfig, apiUrl)
+		}
+	}
+}
+
+/* Test Retry */
+
+func TestGetRetry(t *testing.T) {
+	retries := func(n int) *int {
+		return &n
+	}
+
+	tests := []struct {
+		retries  *int
+		expected float64
+	}{
+		{nil, pvrDefaultRetry.MaxAttempts},
+		{retries(0), 0},
+		{retries(3), 3},
+	}
+
+	for _, tc := range tests {
+		if retry := getRetry(&config.Pvr{HTTP: config.HTTP{Retries: tc.retries}}); retry.MaxAttempts != tc.expected {
+			t.Errorf("Expected %v max attempts for %v retries but got: %v", tc.expected, tc.retries, retry.MaxAttempts)
+		}
+	}
+}
+
+/* Test Search Commands */
+
+func TestSearchMediaItemsAbandoned(t *testing.T) {
+	for _, pvrType := range []string{"sonarr_v3", "radarr_v2", "radarr_v3"} {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// the command is accepted, then the search is cancelled while retrieving its status
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/command") {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"id": 1}`))
+				return
+			}
+
+			cancel()
+			<-r.Context().Done()
+		}))
+
+		p, err := Get("test", pvrType, &config.Pvr{Type: pvrType, URL: srv.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		command, err := p.SearchMediaItems(ctx, []int{1})
+		if err == nil || command == nil || command.Status != CommandStatusAbandoned {
+			t.Errorf("Expected abandoned command for %s but got: %+v (err: %v)", pvrType, command, err)
+		}
+
+		cancel()
+		srv.Close()
+	}
+}
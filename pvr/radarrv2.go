@@ -1,6 +1,7 @@
 package pvr
 
 import (
+	"context"
 	"fmt"
 	"github.com/imroc/req"
 	"github.com/l3uddz/wantarr/config"
@@ -8,6 +9,7 @@ import (
 	"github.com/l3uddz/wantarr/utils/web"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"net/http"
 	"strings"
 	"time"
 )
@@ -19,7 +21,10 @@ type RadarrV2 struct {
 	log        *logrus.Entry
 	apiUrl     string
 	reqHeaders req.Header
+	client     *http.Client
 	timeout    int
+	retry      web.Retry
+	pageSize   int
 }
 
 type RadarrV2Movie struct {
@@ -38,6 +43,12 @@ type RadarrV2Wanted struct {
 	Records       []RadarrV2Movie
 }
 
+type RadarrV2QueueItem struct {
+	Status                string
+	TrackedDownloadStatus string
+	TrackedDownloadState  string
+}
+
 type RadarrV2SystemStatus struct {
 	Version string
 }
@@ -61,35 +72,31 @@ type RadarrV2MovieSearch struct {
 
 /* Initializer */
 
-func NewRadarrV2(name string, c *config.Pvr) *RadarrV2 {
-	// set api url
-	apiUrl := ""
-	if strings.Contains(c.URL, "/api") {
-		apiUrl = c.URL
-	} else {
-		apiUrl = web.JoinURL(c.URL, "/api")
-	}
-
-	// set headers
-	reqHeaders := req.Header{
-		"X-Api-Key": c.ApiKey,
+func NewRadarrV2(name string, c *config.Pvr) (*RadarrV2, error) {
+	// set http client
+	client, err := getHttpClient(c)
+	if err != nil {
+		return nil, err
 	}
 
 	return &RadarrV2{
 		cfg:        c,
-		log:        logger.GetLogger(name),
-		apiUrl:     apiUrl,
-		reqHeaders: reqHeaders,
-		timeout:    pvrDefaultTimeout,
-	}
+		log:        logger.GetLogger(name).WithField("pvr", strings.ToLower(name)),
+		apiUrl:     getApiUrl(c, "/api"),
+		reqHeaders: getRequestHeaders(c),
+		client:     client,
+		timeout:    getTimeout(c),
+		retry:      getRetry(c),
+		pageSize:   getPageSize(c),
+	}, nil
 }
 
 /* Private */
 
-func (p *RadarrV2) getSystemStatus() (*RadarrV2SystemStatus, error) {
+func (p *RadarrV2) getSystemStatus(ctx context.Context) (*RadarrV2SystemStatus, error) {
 	// send request
-	resp, err := web.GetResponse(web.GET, web.JoinURL(p.apiUrl, "/system/status"), p.timeout, p.reqHeaders,
-		&pvrDefaultRetry)
+	resp, err := web.GetResponse(ctx, web.GET, web.JoinURL(p.apiUrl, "/system/status"), p.timeout, p.client, p.reqHeaders,
+		&p.retry)
 	if err != nil {
 		return nil, errors.New("failed retrieving system status api response from radarr")
 	}
@@ -110,10 +117,10 @@ func (p *RadarrV2) getSystemStatus() (*RadarrV2SystemStatus, error) {
 	return &s, nil
 }
 
-func (p *RadarrV2) getCommandStatus(id int) (*RadarrV2CommandStatus, error) {
+func (p *RadarrV2) getCommandStatus(ctx context.Context, id int) (*RadarrV2CommandStatus, error) {
 	// send request
-	resp, err := web.GetResponse(web.GET, web.JoinURL(p.apiUrl, fmt.Sprintf("/command/%d", id)), p.timeout,
-		p.reqHeaders, &pvrDefaultRetry)
+	resp, err := web.GetResponse(ctx, web.GET, web.JoinURL(p.apiUrl, fmt.Sprintf("/command/%d", id)), p.timeout,
+		p.client, p.reqHeaders, &p.retry)
 	if err != nil {
 		return nil, errors.New("failed retrieving command status api response from radarr")
 	}
@@ -134,11 +141,59 @@ func (p *RadarrV2) getCommandStatus(id int) (*RadarrV2CommandStatus, error) {
 	return &s, nil
 }
 
+func (p *RadarrV2) getDiskSpace(ctx context.Context) ([]DiskSpace, error) {
+	// send request
+	resp, err := web.GetResponse(ctx, web.GET, web.JoinURL(p.apiUrl, "/diskspace"), p.timeout, p.client, p.reqHeaders,
+		&p.retry)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed retrieving disk space api response from radarr")
+	}
+	defer resp.Response().Body.Close()
+
+	// validate response
+	if resp.Response().StatusCode != 200 {
+		return nil, fmt.Errorf("failed retrieving valid disk space api response from radarr: %s",
+			resp.Response().Status)
+	}
+
+	// decode response
+	var s []DiskSpace
+	if err := resp.ToJSON(&s); err != nil {
+		return nil, errors.WithMessage(err, "failed decoding disk space api response from radarr")
+	}
+
+	return s, nil
+}
+
+func (p *RadarrV2) getRootFolders(ctx context.Context) ([]RootFolder, error) {
+	// send request
+	resp, err := web.GetResponse(ctx, web.GET, web.JoinURL(p.apiUrl, "/rootfolder"), p.timeout, p.client, p.reqHeaders,
+		&p.retry)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed retrieving root folder api response from radarr")
+	}
+	defer resp.Response().Body.Close()
+
+	// validate response
+	if resp.Response().StatusCode != 200 {
+		return nil, fmt.Errorf("failed retrieving valid root folder api response from radarr: %s",
+			resp.Response().Status)
+	}
+
+	// decode response
+	var s []RootFolder
+	if err := resp.ToJSON(&s); err != nil {
+		return nil, errors.WithMessage(err, "failed decoding root folder api response from radarr")
+	}
+
+	return s, nil
+}
+
 /* Interface Implements */
 
-func (p *RadarrV2) Init() error {
+func (p *RadarrV2) Init(ctx context.Context) error {
 	// retrieve system status
-	status, err := p.getSystemStatus()
+	status, err := p.getSystemStatus(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed initializing radarr pvr")
 	}
@@ -153,43 +208,51 @@ func (p *RadarrV2) Init() error {
 	return nil
 }
 
-func (p *RadarrV2) GetQueueSize() (int, error) {
+func (p *RadarrV2) GetQueue(ctx context.Context) (*Queue, error) {
 	// send request
-	resp, err := web.GetResponse(web.GET, web.JoinURL(p.apiUrl, "/queue"), p.timeout, p.reqHeaders,
-		&pvrDefaultRetry)
+	resp, err := web.GetResponse(ctx, web.GET, web.JoinURL(p.apiUrl, "/queue"), p.timeout, p.client, p.reqHeaders,
+		&p.retry)
 	if err != nil {
-		return 0, errors.WithMessage(err, "failed retrieving queue api response from radarr")
+		return nil, errors.WithMessage(err, "failed retrieving queue api response from radarr")
 	}
 	defer resp.Response().Body.Close()
 
 	// validate response
 	if resp.Response().StatusCode != 200 {
-		return 0, fmt.Errorf("failed retrieving valid queue api response from radarr: %s",
+		return nil, fmt.Errorf("failed retrieving valid queue api response from radarr: %s",
 			resp.Response().Status)
 	}
 
 	// decode response
-	var q []interface{}
+	var q []RadarrV2QueueItem
 	if err := resp.ToJSON(&q); err != nil {
-		return 0, errors.WithMessage(err, "failed decoding queue api response from radarr")
+		return nil, errors.WithMessage(err, "failed decoding queue api response from radarr")
 	}
 
-	queueSize := len(q)
-	p.log.WithField("queue_size", queueSize).Debug("Queue retrieved")
-	return queueSize, nil
+	// process response
+	queue := NewQueue()
+	for _, item := range q {
+		queue.add(item.Status, item.TrackedDownloadStatus, item.TrackedDownloadState)
+	}
+
+	p.log.WithFields(logrus.Fields{
+		"queue_size":   queue.Size,
+		"queue_states": queue.States,
+	}).Debug("Queue retrieved")
+	return queue, nil
 }
 
-func (p *RadarrV2) GetWantedMissing() ([]MediaItem, error) {
+func (p *RadarrV2) GetWantedMissing(ctx context.Context) ([]MediaItem, error) {
 	// logic vars
 	totalRecords := 0
 	var wantedMissing []MediaItem
 
 	page := 1
-	lastPageSize := pvrDefaultPageSize
+	lastPageSize := p.pageSize
 
 	// set params
 	params := req.QueryParam{
-		"pageSize":  pvrDefaultPageSize,
+		"pageSize":  p.pageSize,
 		"monitored": "true",
 	}
 
@@ -206,8 +269,8 @@ func (p *RadarrV2) GetWantedMissing() ([]MediaItem, error) {
 		params["page"] = page
 
 		// send request
-		resp, err := web.GetResponse(web.GET, web.JoinURL(p.apiUrl, "/wanted/missing"), p.timeout,
-			p.reqHeaders, &pvrDefaultRetry, params)
+		resp, err := web.GetResponse(ctx, web.GET, web.JoinURL(p.apiUrl, "/wanted/missing"), p.timeout,
+			p.client, p.reqHeaders, &p.retry, params)
 		if err != nil {
 			return nil, errors.WithMessage(err, "failed retrieving wanted missing api response from radarr")
 		}
@@ -256,17 +319,17 @@ func (p *RadarrV2) GetWantedMissing() ([]MediaItem, error) {
 	return wantedMissing, nil
 }
 
-func (p *RadarrV2) GetWantedCutoff() ([]MediaItem, error) {
+func (p *RadarrV2) GetWantedCutoff(ctx context.Context) ([]MediaItem, error) {
 	// logic vars
 	totalRecords := 0
 	var wantedCutoff []MediaItem
 
 	page := 1
-	lastPageSize := pvrDefaultPageSize
+	lastPageSize := p.pageSize
 
 	// set params
 	params := req.QueryParam{
-		"pageSize":  pvrDefaultPageSize,
+		"pageSize":  p.pageSize,
 		"monitored": "true",
 	}
 
@@ -283,8 +346,8 @@ func (p *RadarrV2) GetWantedCutoff() ([]MediaItem, error) {
 		params["page"] = page
 
 		// send request
-		resp, err := web.GetResponse(web.GET, web.JoinURL(p.apiUrl, "/wanted/cutoff"), p.timeout,
-			p.reqHeaders, &pvrDefaultRetry, params)
+		resp, err := web.GetResponse(ctx, web.GET, web.JoinURL(p.apiUrl, "/wanted/cutoff"), p.timeout,
+			p.client, p.reqHeaders, &p.retry, params)
 		if err != nil {
 			return nil, errors.WithMessage(err, "failed retrieving wanted cutotff unmet api response from radarr")
 		}
@@ -328,7 +391,7 @@ func (p *RadarrV2) GetWantedCutoff() ([]MediaItem, error) {
 	return wantedCutoff, nil
 }
 
-func (p *RadarrV2) SearchMediaItems(mediaItemIds []int) (bool, error) {
+func (p *RadarrV2) SearchMediaItems(ctx context.Context, mediaItemIds []int) (*SearchCommand, error) {
 	// set request data
 	payload := RadarrV2MovieSearch{
 		Name:   "moviesSearch",
@@ -336,51 +399,136 @@ func (p *RadarrV2) SearchMediaItems(mediaItemIds []int) (bool, error) {
 	}
 
 	// send request
-	resp, err := web.GetResponse(web.POST, web.JoinURL(p.apiUrl, "/command"), p.timeout, p.reqHeaders,
-		&pvrDefaultRetry, req.BodyJSON(&payload))
+	resp, err := web.GetResponse(ctx, web.POST, web.JoinURL(p.apiUrl, "/command"), p.timeout, p.client, p.reqHeaders,
+		&p.retry, req.BodyJSON(&payload))
 	if err != nil {
-		return false, errors.WithMessage(err, "failed retrieving command api response from radarr")
+		return nil, errors.WithMessage(err, "failed retrieving command api response from radarr")
 	}
 	defer resp.Response().Body.Close()
 
 	// validate response
 	if resp.Response().StatusCode != 201 {
-		return false, fmt.Errorf("failed retrieving valid command api response from radarr: %s",
+		return nil, fmt.Errorf("failed retrieving valid command api response from radarr: %s",
 			resp.Response().Status)
 	}
 
 	// decode response
 	var q RadarrV2CommandResponse
 	if err := resp.ToJSON(&q); err != nil {
-		return false, errors.WithMessage(err, "failed decoding command api response from radarr")
+		return nil, errors.WithMessage(err, "failed decoding command api response from radarr")
 	}
 
 	// monitor search status
 	p.log.WithField("command_id", q.Id).Debug("Monitoring search status")
 
+	command := &SearchCommand{
+		Id:      q.Id,
+		Status:  "queued",
+		Started: time.Now().UTC(),
+	}
+	maxDuration := getCommandMaxDuration(p.cfg)
+	pollInterval := getCommandPollInterval(p.cfg)
+
 	for {
 		// retrieve command status
-		searchStatus, err := p.getCommandStatus(q.Id)
+		searchStatus, err := p.getCommandStatus(ctx, q.Id)
 		if err != nil {
-			return false, errors.Wrapf(err, "failed retrieving command status from radarr for: %d", q.Id)
+			command.Ended = time.Now().UTC()
+
+			// the pvr carries on with the command when cancelled while retrieving its status
+			if ctx.Err() != nil {
+				command.Status = CommandStatusAbandoned
+				return command, errors.WithMessage(ctx.Err(), "search abandoned while being monitored")
+			}
+
+			return command, errors.Wrapf(err, "failed retrieving command status from radarr for: %d", q.Id)
 		}
 
+		command.Status = searchStatus.Status
+		command.Message = searchStatus.Message
+
 		p.log.WithFields(logrus.Fields{
 			"command_id": q.Id,
 			"status":     searchStatus.Status,
 		}).Debug("Status retrieved")
 
 		// is status complete?
-		if searchStatus.Status == "completed" {
+		switch searchStatus.Status {
+		case "completed":
+			command.Ended = time.Now().UTC()
+			return command, nil
+		case "failed":
+			command.Ended = time.Now().UTC()
+			return command, fmt.Errorf("search failed with message: %q", searchStatus.Message)
+		case "aborted", "cancelled":
+			command.Ended = time.Now().UTC()
+			return command, errors.WithMessagef(ErrCommandAborted, "search %s with message: %q",
+				searchStatus.Status, searchStatus.Message)
+		case "started", "queued":
 			break
-		} else if searchStatus.Status == "failed" {
-			return false, fmt.Errorf("search failed with message: %q", searchStatus.Message)
-		} else if searchStatus.Status != "started" && searchStatus.Status != "queued" {
-			return false, fmt.Errorf("search failed with unexpected status %q, message: %q", searchStatus.Status, searchStatus.Message)
+		default:
+			command.Ended = time.Now().UTC()
+			return command, fmt.Errorf("search failed with unexpected status %q, message: %q", searchStatus.Status, searchStatus.Message)
+		}
+
+		// has the command exceeded its maximum duration?
+		if time.Since(command.Started) >= maxDuration {
+			command.Status = CommandStatusTimedOut
+			command.Ended = time.Now().UTC()
+			return command, errors.WithMessagef(ErrCommandTimeout, "search still %s after %s",
+				searchStatus.Status, maxDuration)
 		}
 
-		time.Sleep(10 * time.Second)
+		// wait before next status check
+		select {
+		case <-ctx.Done():
+			command.Status = CommandStatusAbandoned
+			command.Ended = time.Now().UTC()
+			return command, errors.WithMessage(ctx.Err(), "search abandoned while being monitored")
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (p *RadarrV2) GetRootFolderSpace(ctx context.Context) ([]DiskSpace, error) {
+	// retrieve root folders
+	rootFolders, err := p.getRootFolders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// retrieve disk space
+	disks, err := p.getDiskSpace(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	spaces := getRootFolderSpace(rootFolders, disks)
+	p.log.WithField("root_folders", len(spaces)).Debug("Root folder disk space retrieved")
+	return spaces, nil
+}
+
+func (p *RadarrV2) GetDownloadClients(ctx context.Context) ([]DownloadClient, error) {
+	// send request
+	resp, err := web.GetResponse(ctx, web.GET, web.JoinURL(p.apiUrl, "/downloadclient"), p.timeout, p.client, p.reqHeaders,
+		&p.retry)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed retrieving download client api response from radarr")
+	}
+	defer resp.Response().Body.Close()
+
+	// validate response
+	if resp.Response().StatusCode != 200 {
+		return nil, fmt.Errorf("failed retrieving valid download client api response from radarr: %s",
+			resp.Response().Status)
+	}
+
+	// decode response
+	var c []DownloadClient
+	if err := resp.ToJSON(&c); err != nil {
+		return nil, errors.WithMessage(err, "failed decoding download client api response from radarr")
 	}
 
-	return true, nil
+	p.log.WithField("download_clients", len(c)).Debug("Download clients retrieved")
+	return c, nil
 }
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/l3uddz/wantarr/logger"
+	stringutils "github.com/l3uddz/wantarr/utils/strings"
+	"github.com/pkg/errors"
+)
+
+var (
+	// Logging
+	log = logger.GetLogger("server")
+)
+
+/* Structs */
+
+type Server struct {
+	apiKey string
+	mux    *http.ServeMux
+	http   *http.Server
+
+	routes    map[string]map[string]http.HandlerFunc
+	routesMtx sync.RWMutex
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+/* Initializer */
+
+func New(bind string, apiKey string) *Server {
+	mux := http.NewServeMux()
+
+	return &Server{
+		apiKey: apiKey,
+		mux:    mux,
+		http: &http.Server{
+			Addr:              bind,
+			Handler:           mux,
+			ReadHeaderTimeout: 30 * time.Second,
+		},
+		routes: make(map[string]map[string]http.HandlerFunc),
+	}
+}
+
+/* Public */
+
+func (s *Server) Handle(method string, pattern string, handler http.HandlerFunc) {
+	s.routesMtx.Lock()
+	defer s.routesMtx.Unlock()
+
+	// register pattern with mux
+	if _, ok := s.routes[pattern]; !ok {
+		s.routes[pattern] = make(map[string]http.HandlerFunc)
+		s.mux.HandleFunc(pattern, s.dispatch(pattern))
+	}
+
+	s.routes[pattern][method] = handler
+}
+
+func (s *Server) Start() error {
+	log.Infof("Using %s = %q", stringutils.StringLeftJust("BIND", " ", 10), s.http.Addr)
+	if s.apiKey == "" {
+		log.Warn("No api key has been set, the http server is unauthenticated")
+	}
+
+	if err := s.http.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return errors.Wrap(err, "failed listening")
+	}
+
+	return nil
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	log.Info("Shutting down http server...")
+	return s.http.Shutdown(ctx)
+}
+
+func PathParams(r *http.Request, prefix string) []string {
+	p := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if p == "" {
+		return nil
+	}
+
+	return strings.Split(p, "/")
+}
+
+func ReadJSON(r *http.Request, v interface{}) error {
+	if r.Body == nil || r.ContentLength == 0 {
+		return nil
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return errors.Wrap(err, "failed decoding request body")
+	}
+
+	return nil
+}
+
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.WithError(err).Error("Failed encoding response")
+	}
+}
+
+func WriteError(w http.ResponseWriter, status int, err error) {
+	WriteJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+/* Private */
+
+func (s *Server) dispatch(pattern string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// validate api key
+		if !s.authorized(r) {
+			WriteError(w, http.StatusUnauthorized, errors.New("invalid api key"))
+			return
+		}
+
+		// find handler for method
+		s.routesMtx.RLock()
+		handler, ok := s.routes[pattern][r.Method]
+		s.routesMtx.RUnlock()
+
+		if !ok {
+			WriteError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+
+		log.WithField("remote_addr", r.RemoteAddr).Tracef("Request: %s %s", r.Method, r.URL.Path)
+		handler(w, r)
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.apiKey == "" {
+		return true
+	}
+
+	key := r.Header.Get("X-Api-Key")
+	if key == "" {
+		key = r.URL.Query().Get("apikey")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(key), []byte(s.apiKey)) == 1
+}
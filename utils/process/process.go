@@ -0,0 +1,19 @@
+//go:build !windows && !nacl && !plan9
+// +build !windows,!nacl,!plan9
+
+package process
+
+import (
+	"syscall"
+)
+
+// Running determines whether a process with the pid exists on this host
+func Running(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	// signal 0 only checks the process exists (EPERM means it belongs to another user)
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
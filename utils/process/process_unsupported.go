@@ -0,0 +1,23 @@
+//go:build windows || nacl || plan9
+// +build windows nacl plan9
+
+package process
+
+import (
+	"os"
+)
+
+// Running determines whether a process with the pid exists on this host
+func Running(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	_ = p.Release()
+	return true
+}
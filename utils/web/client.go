@@ -0,0 +1,102 @@
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+/* Structs */
+
+type ClientOptions struct {
+	// CAFile is a pem bundle of certificate authorities trusted in addition to the system ones
+	CAFile string
+	// InsecureSkipVerify disables verification of the server certificate
+	InsecureSkipVerify bool
+	// CertFile and KeyFile are the pem client certificate and key used for mutual tls
+	CertFile string
+	KeyFile  string
+	// Proxy is a http, https or socks5 proxy url, the environment proxy settings are used when empty
+	Proxy string
+	// UnixSocket is the path of a unix socket to connect to instead of the request host
+	UnixSocket string
+}
+
+/* Public */
+
+// NewClient returns a http client for the options, to be passed to GetResponse
+func NewClient(opts ClientOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	// set proxy
+	if opts.Proxy != "" {
+		proxyUrl, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed parsing proxy url: %q", opts.Proxy)
+		}
+
+		switch proxyUrl.Scheme {
+		case "http", "https", "socks5":
+			transport.Proxy = http.ProxyURL(proxyUrl)
+		default:
+			return nil, errors.Errorf("unsupported proxy scheme: %q (supported: http, https, socks5)",
+				proxyUrl.Scheme)
+		}
+	}
+
+	// set unix socket
+	if opts.UnixSocket != "" {
+		if opts.Proxy != "" {
+			return nil, errors.New("proxy can not be used with a unix socket")
+		}
+
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", opts.UnixSocket)
+		}
+	}
+
+	// set tls
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		pem, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed reading ca file: %q", opts.CAFile)
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("failed parsing certificates from ca file: %q", opts.CAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed loading client certificate: %q / %q", opts.CertFile,
+				opts.KeyFile)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport}, nil
+}
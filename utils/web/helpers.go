@@ -1,9 +1,30 @@
 package web
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
 	"path"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// UnixSocketURL is the base url of requests sent over a unix socket
+	UnixSocketURL = "http://unix"
+
+	unixScheme = "unix://"
+)
+
+var (
+	maxRetryAfter = 5 * time.Minute
 )
 
 /* Public */
@@ -13,3 +34,97 @@ func JoinURL(base string, paths ...string) string {
 	p := path.Join(paths...)
 	return fmt.Sprintf("%s/%s", strings.TrimRight(base, "/"), strings.TrimLeft(p, "/"))
 }
+
+// UnixSocketPath returns the socket path of a unix socket url, e.g. unix:///var/run/sonarr.sock
+func UnixSocketPath(rawUrl string) (string, bool) {
+	if len(rawUrl) <= len(unixScheme) || !strings.EqualFold(rawUrl[:len(unixScheme)], unixScheme) {
+		return "", false
+	}
+
+	return rawUrl[len(unixScheme):], true
+}
+
+/* Private */
+
+// isTransientError determines whether a request error is likely to succeed when retried. POST requests are not
+// idempotent, so they are only retried when the request cannot have been sent.
+func isTransientError(method HTTPMethod, err error) bool {
+	if method == POST {
+		return isNotSentError(err)
+	}
+
+	// timeouts
+	if os.IsTimeout(err) || isNotSentError(err) {
+		return true
+	}
+
+	// connections reset / closed
+	for _, target := range []error{
+		syscall.ECONNRESET,
+		syscall.ECONNABORTED,
+		syscall.EPIPE,
+		io.EOF,
+		io.ErrUnexpectedEOF,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isNotSentError determines whether a request error happened before the request was sent
+func isNotSentError(err error) bool {
+	// dns failures
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	// connections refused / failed
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// retryAfter parses a Retry-After header value, in seconds or a http date, capped at maxRetryAfter
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	var d time.Duration
+	if secs, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		d = t.Sub(now)
+	} else {
+		return 0, false
+	}
+
+	switch {
+	case d < 0:
+		return 0, true
+	case d > maxRetryAfter:
+		return maxRetryAfter, true
+	default:
+		return d, true
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -1,8 +1,9 @@
 package web
 
 import (
+	"context"
 	"io/ioutil"
-	"os"
+	"net/http"
 	"strings"
 	"time"
 
@@ -54,15 +55,10 @@ func init() {
 
 /* Public */
 
-func GetResponse(method HTTPMethod, requestUrl string, timeout int, v ...interface{}) (*req.Resp, error) {
+func GetResponse(ctx context.Context, method HTTPMethod, requestUrl string, timeout int, v ...interface{}) (*req.Resp,
+	error) {
 	inputs := make([]interface{}, 0)
-
-	// prepare client
 	client := httpClient
-	if timeout > 0 {
-		client.Timeout = time.Duration(timeout) * time.Second
-	}
-	inputs = append(inputs, &client)
 
 	// prepare request
 	var retry Retry
@@ -72,11 +68,19 @@ func GetResponse(method HTTPMethod, requestUrl string, timeout int, v ...interfa
 			retry = *vT
 		case Retry:
 			retry = vT
+		case *http.Client:
+			client = *vT
 		default:
 			inputs = append(inputs, vT)
 		}
 	}
 
+	// prepare client
+	if timeout > 0 {
+		client.Timeout = time.Duration(timeout) * time.Second
+	}
+	inputs = append([]interface{}{&client, ctx}, inputs...)
+
 	// Response var
 	var resp *req.Resp
 	var err error
@@ -89,6 +93,10 @@ func GetResponse(method HTTPMethod, requestUrl string, timeout int, v ...interfa
 			resp, err = req.Get(requestUrl, inputs...)
 		case POST:
 			resp, err = req.Post(requestUrl, inputs...)
+		case PUT:
+			resp, err = req.Put(requestUrl, inputs...)
+		case DELETE:
+			resp, err = req.Delete(requestUrl, inputs...)
 		default:
 			log.Error("Request method has not been implemented")
 			return nil, errors.New("request method has not been implemented")
@@ -97,14 +105,16 @@ func GetResponse(method HTTPMethod, requestUrl string, timeout int, v ...interfa
 		// validate response
 		if err != nil {
 			log.WithError(err).Debugf("Failed requesting: %q", requestUrl)
-			if os.IsTimeout(err) {
+			if ctx.Err() == nil && isTransientError(method, err) {
 				if retry.MaxAttempts == 0 || retry.Attempt() >= retry.MaxAttempts {
 					return nil, err
 				}
 
 				d := retry.Duration()
 				log.Debugf("Retrying failed request in %s: %q", d, requestUrl)
-				time.Sleep(d)
+				if err := sleepContext(ctx, d); err != nil {
+					return nil, err
+				}
 				continue
 			}
 
@@ -123,11 +133,16 @@ func GetResponse(method HTTPMethod, requestUrl string, timeout int, v ...interfa
 			// close response body
 			_ = resp.Response().Body.Close()
 
-			// retry
+			// retry (waiting as long as the server asked)
 			d := retry.Duration()
+			if ra, ok := retryAfter(resp.Response().Header.Get("Retry-After"), time.Now()); ok && ra > d {
+				d = ra
+			}
 			log.Debugf("Retrying failed request in %s: %d - %q", d, resp.Response().StatusCode, requestUrl)
 
-			time.Sleep(d)
+			if err := sleepContext(ctx, d); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
@@ -144,7 +159,9 @@ func GetResponse(method HTTPMethod, requestUrl string, timeout int, v ...interfa
 				d := retry.Duration()
 				log.Debugf("Retrying failed request in %s: %d %s - %q", d, resp.Response().StatusCode, contentType, requestUrl)
 
-				time.Sleep(d)
+				if err := sleepContext(ctx, d); err != nil {
+					return nil, err
+				}
 				continue
 			}
 		}
@@ -155,9 +172,10 @@ func GetResponse(method HTTPMethod, requestUrl string, timeout int, v ...interfa
 	return resp, err
 }
 
-func GetBodyBytes(method HTTPMethod, requestUrl string, timeout int, v ...interface{}) ([]byte, error) {
+func GetBodyBytes(ctx context.Context, method HTTPMethod, requestUrl string, timeout int, v ...interface{}) ([]byte,
+	error) {
 	// send request
-	resp, err := GetResponse(method, requestUrl, timeout, v...)
+	resp, err := GetResponse(ctx, method, requestUrl, timeout, v...)
 	if err != nil {
 		return nil, err
 	}
@@ -177,8 +195,9 @@ func GetBodyBytes(method HTTPMethod, requestUrl string, timeout int, v ...interf
 	return body, nil
 }
 
-func GetBodyString(method HTTPMethod, requestUrl string, timeout int, v ...interface{}) (string, error) {
-	bodyBytes, err := GetBodyBytes(method, requestUrl, timeout, v...)
+func GetBodyString(ctx context.Context, method HTTPMethod, requestUrl string, timeout int, v ...interface{}) (string,
+	error) {
+	bodyBytes, err := GetBodyBytes(ctx, method, requestUrl, timeout, v...)
 	if err != nil {
 		return "", err
 	}
@@ -1,15 +1,22 @@
 package web
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/url"
 	"os"
+	"syscall"
 	"testing"
+	"time"
 )
 
 /* Test Get Response Timeout */
 
 func TestGetResponseTimeout(t *testing.T) {
 	// send request
-	resp, err := GetResponse(GET, "https://httpbin.davecheney.com/delay/5", 3)
+	resp, err := GetResponse(context.Background(), GET, "https://httpbin.davecheney.com/delay/5", 3)
 	if err != nil && !os.IsTimeout(err) {
 		t.Errorf("Expected timeout in 3 seconds but got error: %v", err)
 		return
@@ -18,3 +25,75 @@ func TestGetResponseTimeout(t *testing.T) {
 		t.Errorf("Expected timeout in 3 seconds but got no error...")
 	}
 }
+
+/* Test Retry After */
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value      string
+		expected   time.Duration
+		expectedOk bool
+	}{
+		{"", 0, false},
+		{"30", 30 * time.Second, true},
+		{"0", 0, true},
+		{"3600", maxRetryAfter, true},
+		{"Mon, 01 Jun 2020 12:01:00 GMT", time.Minute, true},
+		{"Mon, 01 Jun 2020 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tc := range tests {
+		d, ok := retryAfter(tc.value, now)
+		if d != tc.expected || ok != tc.expectedOk {
+			t.Errorf("Expected %s (%v) for %q but got: %s (%v)", tc.expected, tc.expectedOk, tc.value, d, ok)
+		}
+	}
+}
+
+/* Test Transient Errors */
+
+func TestIsTransientError(t *testing.T) {
+	opErr := func(op string, err error) error {
+		return &url.Error{Op: "Get", URL: "http://localhost", Err: &net.OpError{Op: op, Net: "tcp",
+			Err: os.NewSyscallError(op, err)}}
+	}
+	dnsErr := &url.Error{Op: "Get", URL: "http://localhost", Err: &net.DNSError{Err: "no such host",
+		Name: "localhost"}}
+	eofErr := &url.Error{Op: "Get", URL: "http://localhost", Err: io.EOF}
+
+	tests := []struct {
+		method   HTTPMethod
+		err      error
+		expected bool
+	}{
+		{GET, opErr("dial", syscall.ECONNREFUSED), true},
+		{GET, opErr("read", syscall.ECONNRESET), true},
+		{GET, dnsErr, true},
+		{GET, eofErr, true},
+		{GET, &url.Error{Op: "Get", URL: "http://localhost", Err: context.Canceled}, false},
+		{GET, opErr("read", syscall.EACCES), false},
+		{GET, errors.New("unsupported protocol scheme"), false},
+		{GET, timeoutErr{}, true},
+		{DELETE, eofErr, true},
+		// posts are only retried when not sent
+		{POST, opErr("dial", syscall.ECONNREFUSED), true},
+		{POST, dnsErr, true},
+		{POST, opErr("read", syscall.ECONNRESET), false},
+		{POST, eofErr, false},
+		{POST, timeoutErr{}, false},
+	}
+
+	for _, tc := range tests {
+		if transient := isTransientError(tc.method, tc.err); transient != tc.expected {
+			t.Errorf("Expected transient %v for %d %v but got: %v", tc.expected, tc.method, tc.err, transient)
+		}
+	}
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string { return "i/o timeout" }
+func (timeoutErr) Timeout() bool { return true }
@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"strings"
+	"time"
+
+	"github.com/l3uddz/wantarr/database"
+	"github.com/l3uddz/wantarr/logger"
+	"github.com/l3uddz/wantarr/pvr"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// Logging
+	log = logger.GetLogger("webhook")
+)
+
+/* Structs */
+
+type Payload struct {
+	EventType    string
+	IsUpgrade    bool
+	DeleteReason string
+	Series       *Series
+	Episodes     []Episode
+	Movie        *Movie
+}
+
+type Series struct {
+	Id    int
+	Title string
+}
+
+type Episode struct {
+	Id         int
+	AirDateUtc time.Time
+	Monitored  bool
+}
+
+type Movie struct {
+	Id          int
+	Title       string
+	ReleaseDate string
+	Monitored   bool
+}
+
+type Result struct {
+	EventType    string `json:"event_type"`
+	RemovedItems int    `json:"removed_items"`
+	AddedItems   int    `json:"added_items"`
+}
+
+/* Public */
+
+// Process applies a sonarr / radarr connect webhook payload to the wanted items stored for the pvr.
+func Process(store database.Store, pvrName string, payload *Payload) (*Result, error) {
+	result := &Result{EventType: payload.EventType}
+	itemIds := payload.itemIds()
+
+	l := log.WithFields(logrus.Fields{
+		"pvr":        pvrName,
+		"event_type": payload.EventType,
+		"items":      len(itemIds),
+	})
+
+	var err error
+
+	switch strings.ToLower(payload.EventType) {
+	case "test":
+		l.Info("Received test event")
+		return result, nil
+	case "grab", "download", "upgrade":
+		// media no longer missing, an upgrade may still not meet the cutoff so that is left to the next refresh
+		result.RemovedItems, err = store.DeleteMediaItems(pvrName, "missing", itemIds)
+	case "moviedelete":
+		// media no longer wanted
+		result.RemovedItems, err = store.DeleteMediaItems(pvrName, "", itemIds)
+	case "seriesdelete":
+		// media belonging to the series is no longer wanted
+		if payload.Series == nil {
+			return nil, errors.New("series delete event without series")
+		}
+		result.RemovedItems, err = store.DeleteSeriesMediaItems(pvrName, payload.Series.Id)
+	case "episodefiledelete", "moviefiledelete":
+		// upgraded files are replaced, so the media is not missing
+		if strings.EqualFold(payload.DeleteReason, "upgrade") {
+			break
+		}
+
+		// media without a file no longer has a cutoff to meet, but is now missing when monitored (unmonitored media
+		// is not wanted, and media not known to be monitored is left to the next refresh)
+		result.RemovedItems, err = store.DeleteMediaItems(pvrName, "cutoff", itemIds)
+		if items := payload.monitoredMediaItems(); err == nil && len(items) > 0 {
+			if err = store.SetMediaItems(pvrName, "missing", items); err == nil {
+				result.AddedItems = len(items)
+			}
+		}
+	default:
+		l.Debug("Ignoring unsupported event")
+		return result, nil
+	}
+
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed processing %s event", payload.EventType)
+	}
+
+	l.WithFields(logrus.Fields{
+		"removed_items": result.RemovedItems,
+		"added_items":   result.AddedItems,
+	}).Info("Processed event")
+	return result, nil
+}
+
+/* Private */
+
+func (p *Payload) itemIds() []int {
+	var ids []int
+
+	for _, episode := range p.Episodes {
+		ids = append(ids, episode.Id)
+	}
+
+	if p.Movie != nil {
+		ids = append(ids, p.Movie.Id)
+	}
+
+	return ids
+}
+
+func (p *Payload) monitoredMediaItems() []pvr.MediaItem {
+	var items []pvr.MediaItem
+
+	seriesId := 0
+	if p.Series != nil {
+		seriesId = p.Series.Id
+	}
+
+	for _, episode := range p.Episodes {
+		if !episode.Monitored {
+			continue
+		}
+
+		items = append(items, pvr.MediaItem{
+			ItemId:     episode.Id,
+			SeriesId:   seriesId,
+			AirDateUtc: episode.AirDateUtc,
+		})
+	}
+
+	if p.Movie != nil && p.Movie.Monitored {
+		releaseDate, _ := time.Parse("2006-01-02", p.Movie.ReleaseDate)
+		items = append(items, pvr.MediaItem{
+			ItemId:     p.Movie.Id,
+			AirDateUtc: releaseDate,
+		})
+	}
+
+	return items
+}
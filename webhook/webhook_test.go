@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/l3uddz/wantarr/database"
+	"github.com/l3uddz/wantarr/pvr"
+)
+
+/* Test Process */
+
+func TestProcess(t *testing.T) {
+	airDate := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		payload         Payload
+		expectedRemoved int
+		expectedAdded   int
+		expectedMissing []int
+		expectedCutoff  []int
+	}{
+		{"test", Payload{EventType: "Test"}, 0, 0, []int{1, 2, 3}, []int{4}},
+		{"grab", Payload{EventType: "Grab", Episodes: []Episode{{Id: 1}, {Id: 4}}}, 1, 0, []int{2, 3}, []int{4}},
+		{"download", Payload{EventType: "Download", Episodes: []Episode{{Id: 2}, {Id: 4}}}, 1, 0, []int{1, 3},
+			[]int{4}},
+		{"upgrade", Payload{EventType: "Upgrade", IsUpgrade: true, Episodes: []Episode{{Id: 4}}}, 0, 0,
+			[]int{1, 2, 3}, []int{4}},
+		{"movie delete", Payload{EventType: "MovieDelete", Movie: &Movie{Id: 4}}, 1, 0, []int{1, 2, 3}, nil},
+		{"series delete", Payload{EventType: "SeriesDelete", Series: &Series{Id: 10}}, 2, 0, []int{3},
+			[]int{4}},
+		{"episode file delete", Payload{EventType: "EpisodeFileDelete", Series: &Series{Id: 20},
+			Episodes: []Episode{{Id: 4, AirDateUtc: airDate, Monitored: true}}}, 1, 1, []int{1, 2, 3, 4}, nil},
+		{"episode file delete unmonitored", Payload{EventType: "EpisodeFileDelete", Series: &Series{Id: 20},
+			Episodes: []Episode{{Id: 4, AirDateUtc: airDate}, {Id: 5, AirDateUtc: airDate}}}, 1, 0, []int{1, 2, 3},
+			nil},
+		{"episode file delete upgrade", Payload{EventType: "EpisodeFileDelete", DeleteReason: "upgrade",
+			Series: &Series{Id: 20}, Episodes: []Episode{{Id: 4, Monitored: true}}}, 0, 0, []int{1, 2, 3},
+			[]int{4}},
+		{"unsupported", Payload{EventType: "Rename", Episodes: []Episode{{Id: 1}}}, 0, 0, []int{1, 2, 3},
+			[]int{4}},
+	}
+
+	for _, tc := range tests {
+		store := database.NewMemoryStore()
+		if err := store.SetMediaItems("sonarr", "missing", []pvr.MediaItem{
+			{ItemId: 1, SeriesId: 10, AirDateUtc: airDate},
+			{ItemId: 2, SeriesId: 10, AirDateUtc: airDate},
+			{ItemId: 3, SeriesId: 20, AirDateUtc: airDate},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetMediaItems("sonarr", "cutoff", []pvr.MediaItem{
+			{ItemId: 4, SeriesId: 20, AirDateUtc: airDate},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := Process(store, "sonarr", &tc.payload)
+		if err != nil {
+			t.Errorf("Expected no error for %s but got: %v", tc.name, err)
+			continue
+		}
+
+		if result.RemovedItems != tc.expectedRemoved || result.AddedItems != tc.expectedAdded {
+			t.Errorf("Expected %d removed and %d added items for %s but got: %d and %d", tc.expectedRemoved,
+				tc.expectedAdded, tc.name, result.RemovedItems, result.AddedItems)
+		}
+
+		if missing := storedItemIds(t, store, "missing"); !equalIds(missing, tc.expectedMissing) {
+			t.Errorf("Expected missing items %v for %s but got: %v", tc.expectedMissing, tc.name, missing)
+		}
+		if cutoff := storedItemIds(t, store, "cutoff"); !equalIds(cutoff, tc.expectedCutoff) {
+			t.Errorf("Expected cutoff items %v for %s but got: %v", tc.expectedCutoff, tc.name, cutoff)
+		}
+	}
+}
+
+func storedItemIds(t *testing.T, store database.Store, wantedType string) []int {
+	mediaItems, err := store.GetMediaItems("sonarr", wantedType, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	for _, item := range mediaItems {
+		ids = append(ids, item.Id)
+	}
+	sort.Ints(ids)
+
+	return ids
+}
+
+func equalIds(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for pos := range a {
+		if a[pos] != b[pos] {
+			return false
+		}
+	}
+
+	return true
+}